@@ -0,0 +1,397 @@
+package main
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/registry"
+
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/cmd/webhook/init/configuration"
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/cmd/webhook/init/dnsprovider"
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/cmd/webhook/init/reload"
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/cmd/webhook/init/server"
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/internal/infoblox"
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/internal/zonefile"
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/pkg/webhook"
+)
+
+// options are the flags shared by the commands
+type options struct {
+	configFile string
+	output     string
+	dir        string
+	dryRun     bool
+	sync       bool
+	// owners and delete are the flags of gc
+	owners []string
+	delete bool
+	// ownerID and the TXT registry settings select the records owned by an external-dns instance
+	ownerID                string
+	txtPrefix              string
+	txtSuffix              string
+	txtWildcardReplacement string
+	// args are the arguments following the flags
+	args []string
+}
+
+type command struct {
+	description string
+	run         func(opts options) error
+}
+
+var commands = map[string]command{
+	"serve":    {"run the webhook server (default)", serve},
+	"validate": {"check the configuration and the read access to the grid", validate},
+	"records":  {"print the records the webhook returns to external-dns", records},
+	"zones":    {"print the zones matching the domain filter", zones},
+	"export":   {"write the records of every zone to a zone file", export},
+	"import":   {"apply the records of the zone files given as arguments", importZones},
+	"diff":     {"print what the provider would change to serve the endpoints of the file given as argument", diff},
+	"gc":       {"report the records of owners which are not live, and delete them with -delete", gc},
+}
+
+// zoneLister is implemented by providers which can list the zones they manage
+type zoneLister interface {
+	Zones() ([]string, error)
+}
+
+func serve(opts options) error {
+	fmt.Printf(banner, Version, Gitsha)
+
+	config := configuration.Init(opts.configFile)
+	provider, err := dnsprovider.Init(config)
+	if err != nil {
+		return fmt.Errorf("failed to initialize provider: %w", err)
+	}
+
+	wh := webhook.New(provider)
+	srv := server.Init(config, wh)
+	reloader := reload.New(opts.configFile, config, wh)
+	reloader.WatchConfigFile(config.ConfigReloadInterval)
+	server.ShutdownGracefully(srv, func() {
+		if err := reloader.Reload(); err != nil {
+			log.Errorf("failed to reload configuration, keeping the previous one: %v", err)
+		}
+	})
+	return nil
+}
+
+// validate runs the startup self-check, whether it is enabled or not
+func validate(opts options) error {
+	config, err := configuration.Load(opts.configFile)
+	if err != nil {
+		return err
+	}
+	config.Environment["INFOBLOX_STARTUP_CHECK"] = "true"
+	if _, err = dnsprovider.Init(config); err != nil {
+		return err
+	}
+	fmt.Println("configuration is valid")
+	return nil
+}
+
+// initQuiet initializes the provider, logging only warnings unless LOG_LEVEL is set,
+// so the output isn't mixed with the startup logs
+func initQuiet(opts options) (provider.Provider, error) {
+	if os.Getenv("LOG_LEVEL") == "" {
+		log.SetLevel(log.WarnLevel)
+	}
+	config, err := configuration.Load(opts.configFile)
+	if err != nil {
+		return nil, err
+	}
+	return dnsprovider.Init(config)
+}
+
+// closeProvider closes the provider if it implements io.Closer, so pending notifications are sent
+// and the audit log is closed before the command exits
+func closeProvider(p provider.Provider) {
+	if closer, ok := p.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			log.Errorf("error closing provider: %v", err)
+		}
+	}
+}
+
+func records(opts options) error {
+	p, err := initQuiet(opts)
+	if err != nil {
+		return err
+	}
+	endpoints, err := p.Records(context.Background())
+	if err != nil {
+		return err
+	}
+	return printRecords(os.Stdout, opts.output, endpoints)
+}
+
+// ownedProvider is the provider wrapped in the TXT registry of an owner, like external-dns wraps it.
+// Records are labeled with their owners and the ownership records are changed along with the records.
+type ownedProvider struct {
+	*registry.TXTRegistry
+	zones zoneLister
+}
+
+func (p *ownedProvider) Zones() ([]string, error) {
+	return p.zones.Zones()
+}
+
+// withOwner wraps the provider in the TXT registry of the owner ID, if it is set
+func withOwner(p provider.Provider, opts options) (provider.Provider, error) {
+	if opts.ownerID == "" {
+		return p, nil
+	}
+	lister, ok := p.(zoneLister)
+	if !ok {
+		return nil, errors.New("the provider can't list zones")
+	}
+	txtRegistry, err := registry.NewTXTRegistry(p, opts.txtPrefix, opts.txtSuffix, opts.ownerID, 0,
+		opts.txtWildcardReplacement, nil, nil, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &ownedProvider{TXTRegistry: txtRegistry, zones: lister}, nil
+}
+
+// listZones returns the names of the zones managed by the provider
+func listZones(p provider.Provider) ([]string, error) {
+	lister, ok := p.(zoneLister)
+	if !ok {
+		return nil, errors.New("the provider can't list zones")
+	}
+	return lister.Zones()
+}
+
+func zones(opts options) error {
+	p, err := initQuiet(opts)
+	if err != nil {
+		return err
+	}
+	names, err := listZones(p)
+	if err != nil {
+		return err
+	}
+	return writeOutput(os.Stdout, opts.output, names, func(w io.Writer) {
+		fmt.Fprintln(w, "ZONE")
+		for _, name := range names {
+			fmt.Fprintln(w, name)
+		}
+	})
+}
+
+// export writes a zone file per zone to the directory, or all of them to stdout
+func export(opts options) error {
+	p, err := initQuiet(opts)
+	if err != nil {
+		return err
+	}
+	names, err := listZones(p)
+	if err != nil {
+		return err
+	}
+	endpoints, err := p.Records(context.Background())
+	if err != nil {
+		return err
+	}
+	byZone := map[string][]*endpoint.Endpoint{}
+	for _, ep := range endpoints {
+		zone := infoblox.ZoneOf(names, ep.DNSName)
+		byZone[zone] = append(byZone[zone], ep)
+	}
+	for _, zone := range names {
+		if opts.dir == "" {
+			if err = zonefile.Write(os.Stdout, zone, byZone[zone]); err != nil {
+				return err
+			}
+			continue
+		}
+		if err = writeZoneFile(filepath.Join(opts.dir, zone+".zone"), zone, byZone[zone]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeZoneFile(path, zone string, endpoints []*endpoint.Endpoint) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = zonefile.Write(f, zone, endpoints); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("could not write zone file '%s': %w", path, err)
+	}
+	log.Infof("exported %d records of zone '%s' to '%s'", len(endpoints), zone, path)
+	return f.Close()
+}
+
+// importZones applies the records of the zone files, named <zone>.zone like the exported files.
+// It prints the differences to the records in Infoblox, and applies them unless it's a dry run.
+// Records missing in the files are only deleted with the sync flag, and only if they are owned by the owner ID.
+func importZones(opts options) error {
+	if len(opts.args) == 0 {
+		return errors.New("no zone files given")
+	}
+	if opts.sync && opts.ownerID == "" {
+		return errors.New("-sync requires -owner-id, the records of every owner would be deleted")
+	}
+	var origins []string
+	var desired []*endpoint.Endpoint
+	for _, path := range opts.args {
+		origin := strings.TrimSuffix(filepath.Base(path), ".zone")
+		endpoints, err := readZoneFile(path, origin)
+		if err != nil {
+			return err
+		}
+		origins = append(origins, origin)
+		desired = append(desired, endpoints...)
+	}
+
+	base, err := initQuiet(opts)
+	if err != nil {
+		return err
+	}
+	// the registry of withOwner hides Close, so the base provider is closed
+	defer closeProvider(base)
+	p, err := withOwner(base, opts)
+	if err != nil {
+		return err
+	}
+	if desired, err = p.AdjustEndpoints(desired); err != nil {
+		return err
+	}
+	changes, err := zoneChanges(p, origins, desired, opts.sync, opts.ownerID)
+	if err != nil {
+		return err
+	}
+	if err = zonefile.WriteDiff(os.Stdout, changes); err != nil {
+		return err
+	}
+	if opts.dryRun || !changes.HasChanges() {
+		return nil
+	}
+	return p.ApplyChanges(context.Background(), changes)
+}
+
+func readZoneFile(path, origin string) ([]*endpoint.Endpoint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	endpoints, err := zonefile.Parse(f, origin, path)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse zone file '%s': %w", path, err)
+	}
+	for _, ep := range endpoints {
+		if !infoblox.InZone(ep.DNSName, origin) {
+			return nil, fmt.Errorf("record '%s' of zone file '%s' is out of zone '%s'", ep.DNSName, path, origin)
+		}
+	}
+	return endpoints, nil
+}
+
+// zoneChanges calculates the changes from the current records of the zones to the desired records.
+// Records of sub-zones managed as zones of their own are left out. With the owner ID, only the records
+// it owns are updated and deleted.
+func zoneChanges(p provider.Provider, zones []string, desired []*endpoint.Endpoint, sync bool, ownerID string) (*plan.Changes, error) {
+	names, err := listZones(p)
+	if err != nil {
+		return nil, err
+	}
+	for _, zone := range zones {
+		if !slices.Contains(names, zone) {
+			return nil, fmt.Errorf("zone '%s' isn't managed by the webhook", zone)
+		}
+	}
+	records, err := p.Records(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	var current []*endpoint.Endpoint
+	for _, ep := range records {
+		if slices.Contains(zones, infoblox.ZoneOf(names, ep.DNSName)) {
+			current = append(current, ep)
+		}
+	}
+	var policy plan.Policy = &plan.UpsertOnlyPolicy{}
+	if sync {
+		policy = &plan.SyncPolicy{}
+	}
+	calculated := (&plan.Plan{
+		Current:        current,
+		Desired:        desired,
+		Policies:       []plan.Policy{policy},
+		ManagedRecords: zonefile.ManagedRecords,
+		OwnerID:        ownerID,
+	}).Calculate()
+	return calculated.Changes, nil
+}
+
+func printRecords(out io.Writer, format string, endpoints []*endpoint.Endpoint) error {
+	return writeOutput(out, format, endpoints, func(w io.Writer) {
+		fmt.Fprintln(w, "NAME\tTYPE\tTTL\tTARGETS")
+		for _, ep := range endpoints {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%s\n", ep.DNSName, ep.RecordType, ep.RecordTTL, strings.Join(ep.Targets, ","))
+		}
+	})
+}
+
+// writeOutput writes v in the format: json, yaml, or table rendered by the table function
+func writeOutput(out io.Writer, format string, v any, table func(w io.Writer)) error {
+	switch format {
+	case "table":
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		table(w)
+		return w.Flush()
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case "yaml":
+		// convert to JSON first, so the YAML keys are the JSON names of the fields
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		var doc any
+		if err = json.Unmarshal(data, &doc); err != nil {
+			return err
+		}
+		encoder := yaml.NewEncoder(out)
+		encoder.SetIndent(2)
+		return encoder.Encode(doc)
+	}
+	return fmt.Errorf("unknown output format '%s', expected table, json or yaml", format)
+}
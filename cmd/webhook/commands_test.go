@@ -0,0 +1,274 @@
+package main
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/pkg/webhook"
+)
+
+// zonesProvider returns fixed zones and records
+type zonesProvider struct {
+	provider.BaseProvider
+	zones   []string
+	records []*endpoint.Endpoint
+}
+
+func (p *zonesProvider) Zones() ([]string, error) { return p.zones, nil }
+
+func (p *zonesProvider) Records(context.Context) ([]*endpoint.Endpoint, error) { return p.records, nil }
+
+func (p *zonesProvider) ApplyChanges(context.Context, *plan.Changes) error { return nil }
+
+type closingProvider struct {
+	zonesProvider
+	closed bool
+}
+
+func (p *closingProvider) Close() error {
+	p.closed = true
+	return nil
+}
+
+func TestCloseProvider(t *testing.T) {
+	base := &closingProvider{}
+	p, err := withOwner(base, options{ownerID: "cluster-1"})
+	assert.NoError(t, err)
+	// the registry hides Close, the base provider has to be closed
+	closeProvider(p)
+	assert.False(t, base.closed)
+	closeProvider(base)
+	assert.True(t, base.closed)
+}
+
+func TestPrintRecords(t *testing.T) {
+	endpoints := []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("www.example.com", endpoint.RecordTypeA, 300, "10.0.0.1", "10.0.0.2"),
+		endpoint.NewEndpoint("mail.example.com", endpoint.RecordTypeCNAME, "mx.example.com"),
+	}
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, printRecords(out, "table", endpoints))
+	assert.Equal(t, `NAME              TYPE   TTL  TARGETS
+www.example.com   A      300  10.0.0.1,10.0.0.2
+mail.example.com  CNAME  0    mx.example.com
+`, out.String())
+
+	out.Reset()
+	assert.NoError(t, printRecords(out, "json", endpoints))
+	assert.Contains(t, out.String(), `"dnsName": "www.example.com"`)
+
+	out.Reset()
+	assert.NoError(t, printRecords(out, "yaml", endpoints))
+	assert.Contains(t, out.String(), "- dnsName: www.example.com\n  recordTTL: 300\n  recordType: A\n  targets:\n    - 10.0.0.1\n")
+
+	assert.ErrorContains(t, printRecords(out, "xml", endpoints), "unknown output format 'xml'")
+}
+
+func TestZoneChanges(t *testing.T) {
+	p := &zonesProvider{
+		zones: []string{"example.com", "sub.example.com"},
+		records: []*endpoint.Endpoint{
+			endpoint.NewEndpointWithTTL("www.example.com", endpoint.RecordTypeA, 300, "10.0.0.1"),
+			endpoint.NewEndpointWithTTL("old.example.com", endpoint.RecordTypeA, 300, "10.0.0.2"),
+			endpoint.NewEndpointWithTTL("www.sub.example.com", endpoint.RecordTypeA, 300, "10.0.0.3"),
+		},
+	}
+	desired := []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("www.example.com", endpoint.RecordTypeA, 600, "10.0.0.1"),
+		endpoint.NewEndpointWithTTL("new.example.com", endpoint.RecordTypeA, 300, "10.0.0.4"),
+	}
+
+	changes, err := zoneChanges(p, []string{"example.com"}, desired, false, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []*endpoint.Endpoint{desired[1]}, changes.Create)
+	assert.Equal(t, []*endpoint.Endpoint{p.records[0]}, changes.UpdateOld)
+	assert.Equal(t, endpoint.TTL(600), changes.UpdateNew[0].RecordTTL)
+	assert.Empty(t, changes.Delete)
+
+	// the records of the sub-zone are kept, it's a zone of its own
+	changes, err = zoneChanges(p, []string{"example.com"}, desired, true, "")
+	assert.NoError(t, err)
+	assert.Equal(t, []*endpoint.Endpoint{p.records[1]}, changes.Delete)
+
+	_, err = zoneChanges(p, []string{"example.org"}, desired, false, "")
+	assert.ErrorContains(t, err, "zone 'example.org' isn't managed by the webhook")
+}
+
+func TestZoneChangesOwner(t *testing.T) {
+	p := &zonesProvider{
+		zones: []string{"example.com"},
+		records: []*endpoint.Endpoint{
+			endpoint.NewEndpointWithTTL("old.example.com", endpoint.RecordTypeA, 300, "10.0.0.1"),
+			endpoint.NewEndpointWithTTL("a-old.example.com", endpoint.RecordTypeTXT, 300, "\"heritage=external-dns,external-dns/owner=cluster-1\""),
+			endpoint.NewEndpointWithTTL("manual.example.com", endpoint.RecordTypeA, 300, "10.0.0.2"),
+		},
+	}
+	owned, err := withOwner(p, options{ownerID: "cluster-1"})
+	assert.NoError(t, err)
+
+	// only the records of the owner are deleted, the records created by hand are kept
+	changes, err := zoneChanges(owned, []string{"example.com"}, nil, true, "cluster-1")
+	assert.NoError(t, err)
+	if assert.Len(t, changes.Delete, 1) {
+		assert.Equal(t, "old.example.com", changes.Delete[0].DNSName)
+	}
+
+	err = importZones(options{args: []string{"example.com.zone"}, sync: true})
+	assert.ErrorContains(t, err, "-sync requires -owner-id")
+}
+
+// operationsPlanner translates every change into an operation of the endpoint
+type operationsPlanner struct{}
+
+func (operationsPlanner) Plan(_ context.Context, changes *plan.Changes) ([]webhook.Operation, error) {
+	var operations []webhook.Operation
+	add := func(action string, endpoints []*endpoint.Endpoint) {
+		for _, ep := range endpoints {
+			operations = append(operations, webhook.Operation{Action: action, Name: ep.DNSName, RecordType: ep.RecordType})
+		}
+	}
+	add("CREATE", changes.Create)
+	add("UPDATE", changes.UpdateNew)
+	add("DELETE", changes.Delete)
+	return operations, nil
+}
+
+func TestDiffOperationsOwner(t *testing.T) {
+	base := &zonesProvider{
+		zones: []string{"example.com"},
+		records: []*endpoint.Endpoint{
+			endpoint.NewEndpointWithTTL("old.example.com", endpoint.RecordTypeA, 300, "10.0.0.1"),
+			endpoint.NewEndpointWithTTL("old.example.com", endpoint.RecordTypeTXT, 300, "\"heritage=external-dns,external-dns/owner=cluster-1\""),
+			endpoint.NewEndpointWithTTL("a-old.example.com", endpoint.RecordTypeTXT, 300, "\"heritage=external-dns,external-dns/owner=cluster-1\""),
+			endpoint.NewEndpointWithTTL("manual.example.com", endpoint.RecordTypeA, 300, "10.0.0.2"),
+		},
+	}
+	desired := []*endpoint.Endpoint{endpoint.NewEndpointWithTTL("new.example.com", endpoint.RecordTypeA, 300, "10.0.0.3")}
+	planner := &planningProvider{Provider: base, planner: operationsPlanner{}}
+	opts := options{ownerID: "cluster-1", sync: true}
+	p, err := withOwner(planner, opts)
+	assert.NoError(t, err)
+
+	// the records of the owner are deleted along with their ownership records, created records get them
+	operations, err := diffOperations(p, planner, desired, opts)
+	assert.NoError(t, err)
+	var summary []string
+	for _, op := range operations {
+		summary = append(summary, op.Action+" "+op.RecordType+" "+op.Name)
+	}
+	assert.ElementsMatch(t, []string{
+		"CREATE A new.example.com",
+		"CREATE TXT new.example.com",
+		"CREATE TXT a-new.example.com",
+		"DELETE A old.example.com",
+		"DELETE TXT old.example.com",
+		"DELETE TXT a-old.example.com",
+	}, summary)
+}
+
+func TestReadEndpoints(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "endpoints.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(`
+- dnsName: www.example.com
+  recordType: A
+  recordTTL: 300
+  targets: [10.0.0.1]
+- {"dnsName": "mail.example.com", "recordType": "CNAME", "targets": ["mx.example.com"]}
+`), 0600))
+	endpoints, err := readEndpoints(path)
+	assert.NoError(t, err)
+	assert.Len(t, endpoints, 2)
+	assert.Equal(t, "www.example.com", endpoints[0].DNSName)
+	assert.Equal(t, endpoint.TTL(300), endpoints[0].RecordTTL)
+	assert.Equal(t, endpoint.Targets{"10.0.0.1"}, endpoints[0].Targets)
+	assert.Equal(t, endpoint.RecordTypeCNAME, endpoints[1].RecordType)
+	assert.Equal(t, endpoint.Targets{"mx.example.com"}, endpoints[1].Targets)
+
+	assert.NoError(t, os.WriteFile(path, []byte(`dnsName: www.example.com`), 0600))
+	_, err = readEndpoints(path)
+	assert.ErrorContains(t, err, "could not parse endpoints file")
+}
+
+func TestPrintOperations(t *testing.T) {
+	operations := []webhook.Operation{
+		{Action: "CREATE", Object: "record:a", Name: "new.example.com", RecordType: "A", Target: "10.0.0.1", TTL: 300, Zone: "example.com"},
+		{Action: "DELETE", Object: "record:cname", Name: "old.example.com", RecordType: "CNAME", Target: "www.example.com", Zone: "example.com", Queued: true},
+		{Action: "UPDATE", Object: "record:a", Name: "www.example.org", RecordType: "A", Target: "10.0.1.1", TTL: 600, Zone: "example.org"},
+	}
+	out := &bytes.Buffer{}
+	assert.NoError(t, printOperations(out, "table", operations))
+	assert.Equal(t, `example.com
+  +  A      new.example.com  10.0.0.1         ttl 300
+  -  CNAME  old.example.com  www.example.com  ttl 0  queued for approval
+example.org
+  ~  A  www.example.org  10.0.1.1  ttl 600
+`, out.String())
+
+	out.Reset()
+	assert.NoError(t, printOperations(out, "table", nil))
+	assert.Equal(t, "no changes\n", out.String())
+}
+
+func TestOrphans(t *testing.T) {
+	owned := endpoint.NewEndpoint("www.example.com", endpoint.RecordTypeA, "10.0.0.1")
+	owned.Labels[endpoint.OwnerLabelKey] = "ea-cluster"
+	p := &zonesProvider{
+		records: []*endpoint.Endpoint{
+			endpoint.NewEndpoint("live.example.com", endpoint.RecordTypeA, "10.0.0.2"),
+			endpoint.NewEndpoint("a-live.example.com", endpoint.RecordTypeTXT, "\"heritage=external-dns,external-dns/owner=live\""),
+			endpoint.NewEndpoint("gone.example.com", endpoint.RecordTypeCNAME, "live.example.com"),
+			endpoint.NewEndpoint("reg-cname-gone.example.com", endpoint.RecordTypeTXT, "\"heritage=external-dns,external-dns/owner=gone\""),
+			endpoint.NewEndpoint("old.example.com", endpoint.RecordTypeA, "10.0.0.3"),
+			endpoint.NewEndpoint("reg-old.example.com", endpoint.RecordTypeTXT, "heritage=external-dns,external-dns/owner=gone", "v=spf1 -all"),
+			endpoint.NewEndpoint("manual.example.com", endpoint.RecordTypeA, "10.0.0.4"),
+			owned,
+		},
+	}
+
+	orphaned, err := orphans(context.Background(), p, options{owners: []string{"live"}, txtPrefix: "reg-"})
+	assert.NoError(t, err)
+	names := map[string]string{}
+	for _, ep := range orphaned {
+		names[ep.RecordType+" "+ep.DNSName] = ep.Labels[endpoint.OwnerLabelKey] + " " + strings.Join(ep.Targets, ",")
+	}
+	assert.Equal(t, map[string]string{
+		"TXT reg-cname-gone.example.com": "gone \"heritage=external-dns,external-dns/owner=gone\"",
+		"TXT reg-old.example.com":        "gone heritage=external-dns,external-dns/owner=gone",
+		"CNAME gone.example.com":         "gone live.example.com",
+		"A old.example.com":              "gone 10.0.0.3",
+		"A www.example.com":              "ea-cluster 10.0.0.1",
+	}, names)
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, printOrphans(out, "table", orphaned[:1]))
+	assert.Equal(t, "NAME                        TYPE  OWNER  TARGETS\n"+
+		"reg-cname-gone.example.com  TXT   gone   \"heritage=external-dns,external-dns/owner=gone\"\n", out.String())
+}
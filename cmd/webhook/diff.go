@@ -0,0 +1,161 @@
+package main
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/internal/infoblox"
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/pkg/webhook"
+)
+
+// diffActions are the signs of the operations in the diff
+var diffActions = map[string]string{
+	"CREATE": "+",
+	"UPDATE": "~",
+	"DELETE": "-",
+}
+
+// diff prints the operations the provider would send to Infoblox to turn the current records into
+// the endpoints of the file given as argument, without changing anything
+func diff(opts options) error {
+	if len(opts.args) != 1 {
+		return errors.New("expected the endpoints file as the only argument")
+	}
+	desired, err := readEndpoints(opts.args[0])
+	if err != nil {
+		return err
+	}
+
+	base, err := initQuiet(opts)
+	if err != nil {
+		return err
+	}
+	pp, ok := base.(webhook.PlanProvider)
+	if !ok {
+		return errors.New("the provider can't preview changes")
+	}
+	planner := &planningProvider{Provider: base, planner: pp}
+	p, err := withOwner(planner, opts)
+	if err != nil {
+		return err
+	}
+	operations, err := diffOperations(p, planner, desired, opts)
+	if err != nil {
+		return err
+	}
+	return printOperations(os.Stdout, opts.output, operations)
+}
+
+// diffOperations plans the changes to the desired endpoints and returns the operations of the planner.
+// With the owner ID, p is the TXT registry, which adds the changes of the ownership records.
+func diffOperations(p provider.Provider, planner *planningProvider, desired []*endpoint.Endpoint, opts options) ([]webhook.Operation, error) {
+	names, err := listZones(p)
+	if err != nil {
+		return nil, err
+	}
+	if desired, err = p.AdjustEndpoints(desired); err != nil {
+		return nil, err
+	}
+	for _, ep := range desired {
+		if infoblox.ZoneOf(names, ep.DNSName) == "" {
+			log.Warnf("'%s' is in none of the zones, it won't be created", ep.DNSName)
+		}
+	}
+
+	changes, err := zoneChanges(p, names, desired, opts.sync, opts.ownerID)
+	if err != nil {
+		return nil, err
+	}
+	if err = p.ApplyChanges(context.Background(), changes); err != nil {
+		return nil, err
+	}
+	return planner.operations, nil
+}
+
+// planningProvider translates the changes into the operations of the provider instead of applying them
+type planningProvider struct {
+	provider.Provider
+	planner    webhook.PlanProvider
+	operations []webhook.Operation
+}
+
+func (p *planningProvider) Zones() ([]string, error) {
+	return listZones(p.Provider)
+}
+
+func (p *planningProvider) ApplyChanges(ctx context.Context, changes *plan.Changes) (err error) {
+	p.operations, err = p.planner.Plan(ctx, changes)
+	return err
+}
+
+// readEndpoints reads a JSON or YAML list of endpoints in the format of external-dns
+func readEndpoints(path string) ([]*endpoint.Endpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// convert to JSON first, the endpoints are decoded by their JSON names
+	var doc any
+	if err = yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("could not parse endpoints file '%s': %w", path, err)
+	}
+	if data, err = json.Marshal(doc); err != nil {
+		return nil, err
+	}
+	var endpoints []*endpoint.Endpoint
+	if err = json.Unmarshal(data, &endpoints); err != nil {
+		return nil, fmt.Errorf("could not parse endpoints file '%s': %w", path, err)
+	}
+	return endpoints, nil
+}
+
+// printOperations prints the operations, grouped by zone in the table format. The operations are
+// sorted by zone already, operations which would be queued for approval are marked.
+func printOperations(out io.Writer, format string, operations []webhook.Operation) error {
+	return writeOutput(out, format, operations, func(w io.Writer) {
+		if len(operations) == 0 {
+			fmt.Fprintln(w, "no changes")
+			return
+		}
+		zone := ""
+		for i, op := range operations {
+			if i == 0 || op.Zone != zone {
+				zone = op.Zone
+				fmt.Fprintf(w, "%s\n", zone)
+			}
+			queued := ""
+			if op.Queued {
+				queued = "\tqueued for approval"
+			}
+			fmt.Fprintf(w, "  %s\t%s\t%s\t%s\tttl %d%s\n", diffActions[op.Action], op.RecordType, op.Name, op.Target, op.TTL, queued)
+		}
+	})
+}
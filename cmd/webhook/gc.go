@@ -0,0 +1,135 @@
+package main
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+	"sigs.k8s.io/external-dns/provider"
+	"sigs.k8s.io/external-dns/registry"
+)
+
+// gcOwnerID is the owner of the TXT registry reading the owners, it never matches a record
+const gcOwnerID = "external-dns-infoblox-webhook-gc"
+
+// gc reports the records owned by external-dns instances which aren't live anymore, and deletes them
+// with the delete flag. The deletions are applied like the changes of external-dns, so the deletion
+// thresholds and the protected-record policy apply to them.
+func gc(opts options) error {
+	if len(opts.owners) == 0 {
+		return errors.New("no live owners given, every owned record would be orphaned")
+	}
+	p, err := initQuiet(opts)
+	if err != nil {
+		return err
+	}
+	defer closeProvider(p)
+	ctx := context.Background()
+	orphaned, err := orphans(ctx, p, opts)
+	if err != nil {
+		return err
+	}
+	if err = printOrphans(os.Stdout, opts.output, orphaned); err != nil {
+		return err
+	}
+	if !opts.delete || len(orphaned) == 0 {
+		return nil
+	}
+	log.Warnf("deleting %d orphaned records", len(orphaned))
+	return p.ApplyChanges(ctx, &plan.Changes{Delete: orphaned})
+}
+
+// orphans returns the records whose owner isn't live: the records owned by the TXT registry or the
+// owner extensible attribute, and the TXT registry records themselves
+func orphans(ctx context.Context, p provider.Provider, opts options) ([]*endpoint.Endpoint, error) {
+	records, err := p.Records(ctx)
+	if err != nil {
+		return nil, err
+	}
+	isOrphan := func(owner string) bool {
+		return owner != "" && !slices.Contains(opts.owners, owner)
+	}
+
+	var orphaned []*endpoint.Endpoint
+	for _, ep := range records {
+		if ep.RecordType != endpoint.RecordTypeTXT {
+			continue
+		}
+		var targets endpoint.Targets
+		var owner string
+		for _, target := range ep.Targets {
+			labels, err := endpoint.NewLabelsFromStringPlain(target)
+			if err == nil && isOrphan(labels[endpoint.OwnerLabelKey]) {
+				targets = append(targets, target)
+				owner = labels[endpoint.OwnerLabelKey]
+			}
+		}
+		if len(targets) > 0 {
+			txt := ep.DeepCopy()
+			txt.Targets = targets
+			txt.Labels[endpoint.OwnerLabelKey] = owner
+			orphaned = append(orphaned, txt)
+		}
+	}
+
+	// the registry reads the owners from the records fetched already, and labels the records they own
+	txtRegistry, err := registry.NewTXTRegistry(&recordsProvider{Provider: p, records: records},
+		opts.txtPrefix, opts.txtSuffix, gcOwnerID, 0, opts.txtWildcardReplacement, nil, nil, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	owned, err := txtRegistry.Records(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, ep := range owned {
+		if ep.RecordType != endpoint.RecordTypeTXT && isOrphan(ep.Labels[endpoint.OwnerLabelKey]) {
+			orphaned = append(orphaned, ep)
+		}
+	}
+	return orphaned, nil
+}
+
+// recordsProvider returns the records it was created with
+type recordsProvider struct {
+	provider.Provider
+	records []*endpoint.Endpoint
+}
+
+func (p *recordsProvider) Records(context.Context) ([]*endpoint.Endpoint, error) {
+	return p.records, nil
+}
+
+func printOrphans(out io.Writer, format string, endpoints []*endpoint.Endpoint) error {
+	return writeOutput(out, format, endpoints, func(w io.Writer) {
+		fmt.Fprintln(w, "NAME\tTYPE\tOWNER\tTARGETS")
+		for _, ep := range endpoints {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", ep.DNSName, ep.RecordType, ep.Labels[endpoint.OwnerLabelKey], strings.Join(ep.Targets, ","))
+		}
+	})
+}
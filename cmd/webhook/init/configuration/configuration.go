@@ -19,6 +19,7 @@ Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
 */
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/caarlos0/env/v11"
@@ -36,13 +37,49 @@ type Config struct {
 	RegexDomainFilter    string        `env:"REGEXP_DOMAIN_FILTER" envDefault:""`
 	RegexDomainExclusion string        `env:"REGEXP_DOMAIN_FILTER_EXCLUSION" envDefault:""`
 	RegexNameFilter      string        `env:"REGEXP_NAME_FILTER" envDefault:""`
+	// ServerTLSCertFile and ServerTLSKeyFile serve the webhook over HTTPS, the files are reloaded when they change
+	ServerTLSCertFile     string   `env:"SERVER_TLS_CERT_FILE"`
+	ServerTLSKeyFile      string   `env:"SERVER_TLS_KEY_FILE"`
+	ServerTLSMinVersion   string   `env:"SERVER_TLS_MIN_VERSION" envDefault:"1.2"`
+	ServerTLSCipherSuites []string `env:"SERVER_TLS_CIPHER_SUITES" envSeparator:","`
+	// ServerTLSClientCAFile requires callers to present a client certificate signed by the CA,
+	// ServerTLSClientNames restricts the accepted certificates to the listed common or DNS names
+	ServerTLSClientCAFile string   `env:"SERVER_TLS_CLIENT_CA_FILE"`
+	ServerTLSClientNames  []string `env:"SERVER_TLS_CLIENT_NAMES" envSeparator:","`
+	// ServerAuthTokenFile contains the bearer token callers must present
+	ServerAuthTokenFile string `env:"SERVER_AUTH_TOKEN_FILE"`
+	// ServerAdminTokenFile contains the bearer token of the admin endpoints, ServerAdminClientNames the common or DNS
+	// names of the client certificates admitted to them. The admin endpoints are refused without either.
+	ServerAdminTokenFile   string   `env:"SERVER_ADMIN_TOKEN_FILE"`
+	ServerAdminClientNames []string `env:"SERVER_ADMIN_CLIENT_NAMES" envSeparator:","`
+	// ServerHealthPort serves the health check over plain HTTP on a separate port, 0 disables it
+	ServerHealthPort int `env:"SERVER_HEALTH_PORT" envDefault:"0"`
+	// ConfigReloadInterval is the interval the config file is checked for changes, 0 disables the check
+	ConfigReloadInterval time.Duration `env:"CONFIG_RELOAD_INTERVAL" envDefault:"10s"`
+	// Environment is the config file merged with the environmental variables, the infoblox configuration is read from it
+	Environment map[string]string
 }
 
-// Init sets up configuration by reading set environmental variables
-func Init() Config {
-	cfg := Config{}
-	if err := env.Parse(&cfg); err != nil {
-		log.Fatalf("Error reading configuration from environment: %v", err)
+// Init sets up configuration by reading the config file, if set, and the set environmental variables,
+// which take precedence over the config file
+func Init(configFile string) Config {
+	cfg, err := Load(configFile)
+	if err != nil {
+		log.Fatalf("Error reading configuration: %v", err)
 	}
 	return cfg
 }
+
+// Load reads the configuration like Init, but returns the errors
+func Load(configFile string) (Config, error) {
+	environment, err := LoadEnvironment(configFile)
+	if err != nil {
+		return Config{}, err
+	}
+	cfg := Config{Environment: environment}
+	if err = env.ParseWithOptions(&cfg, env.Options{Environment: environment}); err != nil {
+		return Config{}, fmt.Errorf("reading configuration from environment failed: %w", err)
+	}
+	logEffective(environment)
+	return cfg, nil
+}
@@ -0,0 +1,174 @@
+package configuration
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/caarlos0/env/v11"
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/internal/infoblox"
+)
+
+const redacted = "<redacted>"
+
+// documentKeys take a structured value in the config file, which is passed on as JSON
+var documentKeys = []string{"INFOBLOX_POLICY"}
+
+// keys returns the parameters of the environment variables read into Config and infoblox.StartupConfig
+func keys() ([]env.FieldParams, error) {
+	params, err := env.GetFieldParams(&Config{})
+	if err != nil {
+		return nil, err
+	}
+	infobloxParams, err := env.GetFieldParams(&infoblox.StartupConfig{})
+	if err != nil {
+		return nil, err
+	}
+	return append(params, infobloxParams...), nil
+}
+
+// LoadEnvironment reads the configuration file, a YAML or JSON object keyed by the names of the
+// environment variables, and overrides it by the environment variables. Lists are joined by commas,
+// the values of documentKeys are encoded as JSON.
+func LoadEnvironment(path string) (map[string]string, error) {
+	environment := map[string]string{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read config file: %w", err)
+		}
+		var values map[string]any
+		if err = yaml.Unmarshal(data, &values); err != nil {
+			return nil, fmt.Errorf("could not parse config file '%s': %w", path, err)
+		}
+		params, err := keys()
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range values {
+			if !slices.ContainsFunc(params, func(p env.FieldParams) bool { return p.Key == key }) {
+				return nil, fmt.Errorf("unknown key '%s' in config file '%s'", key, path)
+			}
+			if slices.Contains(documentKeys, key) {
+				environment[key], err = toDocument(value)
+			} else {
+				environment[key], err = toEnvValue(value)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("invalid value of '%s' in config file '%s': %w", key, path, err)
+			}
+		}
+	}
+	for key, value := range env.ToMap(os.Environ()) {
+		environment[key] = value
+	}
+	return environment, nil
+}
+
+func toEnvValue(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case []any:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := toEnvValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), nil
+	case map[string]any:
+		return "", fmt.Errorf("expected a scalar or a list")
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+// toDocument encodes a structured value as JSON, strings are taken as they are
+func toDocument(value any) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// logEffective logs the value of every setting, from the environment or the default, with the secrets redacted
+func logEffective(environment map[string]string) {
+	params, err := keys()
+	if err != nil {
+		log.WithError(err).Error("could not list the settings")
+		return
+	}
+	fields := log.Fields{}
+	for _, p := range params {
+		value, ok := environment[p.Key]
+		if !ok {
+			if !p.HasDefaultValue {
+				continue
+			}
+			value = p.DefaultValue
+		}
+		fields[p.Key] = redact(p.Key, value)
+	}
+	log.WithFields(fields).Infof("effective configuration of %d settings", len(fields))
+}
+
+// redact hides the passwords and the credentials in URLs. The URLs of notification receivers
+// carry their token in the path, only their hosts are kept.
+func redact(key, value string) string {
+	switch {
+	case value == "":
+		return value
+	case strings.HasSuffix(key, "PASSWORD"):
+		return redacted
+	case key == "INFOBLOX_PROXY_URL":
+		if u, err := url.Parse(value); err == nil {
+			return u.Redacted()
+		}
+		return redacted
+	case key == "INFOBLOX_NOTIFY_URLS":
+		urls := strings.Split(value, ",")
+		for i, s := range urls {
+			if u, err := url.Parse(s); err == nil && u.Host != "" {
+				urls[i] = u.Scheme + "://" + u.Host + "/" + redacted
+			} else {
+				urls[i] = redacted
+			}
+		}
+		return strings.Join(urls, ",")
+	}
+	return value
+}
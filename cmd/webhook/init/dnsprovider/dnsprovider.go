@@ -42,10 +42,15 @@ func Init(config configuration.Config) (provider.Provider, error) {
 		if config.RegexDomainExclusion != "" {
 			createMsg += fmt.Sprintf("with exclusion: '%s', ", config.RegexDomainExclusion)
 		}
-		domainFilter = endpoint.NewRegexDomainFilter(
-			regexp.MustCompile(config.RegexDomainFilter),
-			regexp.MustCompile(config.RegexDomainExclusion),
-		)
+		regexDomainFilter, err := regexp.Compile(config.RegexDomainFilter)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regexp domain filter '%s': %w", config.RegexDomainFilter, err)
+		}
+		regexDomainExclusion, err := regexp.Compile(config.RegexDomainExclusion)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regexp domain filter exclusion '%s': %w", config.RegexDomainExclusion, err)
+		}
+		domainFilter = endpoint.NewRegexDomainFilter(regexDomainFilter, regexDomainExclusion)
 	} else {
 		if config.DomainFilter != nil && len(config.DomainFilter) > 0 {
 			createMsg += fmt.Sprintf("domain filter: '%s', ", strings.Join(config.DomainFilter, ","))
@@ -56,6 +61,10 @@ func Init(config configuration.Config) (provider.Provider, error) {
 		domainFilter = endpoint.NewDomainFilterWithExclusions(config.DomainFilter, config.ExcludeDomains)
 	}
 
+	if _, err := regexp.Compile(config.RegexNameFilter); err != nil {
+		return nil, fmt.Errorf("invalid regexp name filter '%s': %w", config.RegexNameFilter, err)
+	}
+
 	createMsg = strings.TrimSuffix(createMsg, ", ")
 	if strings.HasSuffix(createMsg, "with ") {
 		createMsg += "no kind of domain filters"
@@ -63,11 +72,20 @@ func Init(config configuration.Config) (provider.Provider, error) {
 	log.Info(createMsg)
 
 	infobloxConfig := infoblox.StartupConfig{}
-	if err := env.Parse(&infobloxConfig); err != nil {
+	if err := env.ParseWithOptions(&infobloxConfig, env.Options{Environment: config.Environment}); err != nil {
 		return nil, fmt.Errorf("reading configuration failed: %v", err)
 	}
 	infobloxConfig.FQDNRegEx = config.RegexDomainFilter
 	infobloxConfig.NameRegEx = config.RegexNameFilter
 
-	return infoblox.NewInfobloxProvider(&infobloxConfig, domainFilter)
+	p, err := infoblox.NewInfobloxProvider(&infobloxConfig, domainFilter)
+	if err != nil {
+		return nil, err
+	}
+	if infobloxConfig.StartupCheck {
+		if err = p.SelfCheck(); err != nil {
+			return nil, fmt.Errorf("startup self-check failed: %w", err)
+		}
+	}
+	return p, nil
 }
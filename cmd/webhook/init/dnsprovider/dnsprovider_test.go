@@ -43,6 +43,7 @@ func TestInit(t *testing.T) {
 				"INFOBLOX_WAPI_USER":     "user123",
 				"INFOBLOX_WAPI_PASSWORD": "password",
 				"INFOBLOX_VERSION":       "2.7.1",
+				"INFOBLOX_STARTUP_CHECK": "false",
 			},
 		},
 		{
@@ -55,6 +56,44 @@ func TestInit(t *testing.T) {
 				"INFOBLOX_WAPI_USER":     "user123",
 				"INFOBLOX_WAPI_PASSWORD": "password",
 				"INFOBLOX_VERSION":       "2.7.1",
+				"INFOBLOX_STARTUP_CHECK": "false",
+			},
+		},
+		{
+			name: "invalid regexp domain filter",
+			config: configuration.Config{
+				RegexDomainFilter: "(domain.com",
+			},
+			env: map[string]string{
+				"INFOBLOX_WAPI_USER":     "user123",
+				"INFOBLOX_WAPI_PASSWORD": "password",
+				"INFOBLOX_VERSION":       "2.7.1",
+				"INFOBLOX_STARTUP_CHECK": "false",
+			},
+			expectedError: "invalid regexp domain filter '(domain.com'",
+		},
+		{
+			name: "invalid regexp name filter",
+			config: configuration.Config{
+				RegexNameFilter: "[a-",
+			},
+			env: map[string]string{
+				"INFOBLOX_WAPI_USER":     "user123",
+				"INFOBLOX_WAPI_PASSWORD": "password",
+				"INFOBLOX_VERSION":       "2.7.1",
+				"INFOBLOX_STARTUP_CHECK": "false",
+			},
+			expectedError: "invalid regexp name filter '[a-'",
+		},
+		{
+			name: "infoblox config from config file",
+			config: configuration.Config{
+				Environment: map[string]string{
+					"INFOBLOX_WAPI_USER":     "user123",
+					"INFOBLOX_WAPI_PASSWORD": "password",
+					"INFOBLOX_VERSION":       "2.7.1",
+					"INFOBLOX_STARTUP_CHECK": "false",
+				},
 			},
 		},
 		{
@@ -0,0 +1,111 @@
+package reload
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/cmd/webhook/init/configuration"
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/cmd/webhook/init/dnsprovider"
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/pkg/webhook"
+)
+
+// Reloader rebuilds the provider from the configuration and swaps it into the webhook
+type Reloader struct {
+	configFile string
+	webhook    *webhook.Webhook
+	mu         sync.Mutex
+	config     configuration.Config
+}
+
+// New creates a Reloader of the webhook started with the configuration
+func New(configFile string, config configuration.Config, wh *webhook.Webhook) *Reloader {
+	return &Reloader{configFile: configFile, webhook: wh, config: config}
+}
+
+// Reload reads the configuration again and replaces the provider. The server settings
+// require a restart. When the new configuration is invalid, the previous provider keeps serving.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	config, err := configuration.Load(r.configFile)
+	if err != nil {
+		return err
+	}
+	provider, err := dnsprovider.Init(config)
+	if err != nil {
+		return err
+	}
+	if serverSettingsChanged(r.config, config) {
+		log.Warn("server settings changed, restart the webhook to apply them")
+	}
+	r.webhook.SetProvider(provider)
+	r.config = config
+	log.Info("configuration reloaded")
+	return nil
+}
+
+// serverSettingsChanged returns true if any of the Server fields differ
+func serverSettingsChanged(previous, current configuration.Config) bool {
+	p, c := reflect.ValueOf(previous), reflect.ValueOf(current)
+	for i := 0; i < p.NumField(); i++ {
+		if strings.HasPrefix(p.Type().Field(i).Name, "Server") &&
+			!reflect.DeepEqual(p.Field(i).Interface(), c.Field(i).Interface()) {
+			return true
+		}
+	}
+	return false
+}
+
+// WatchConfigFile reloads the configuration in the background when the config file changes
+func (r *Reloader) WatchConfigFile(interval time.Duration) {
+	if r.configFile == "" || interval <= 0 {
+		return
+	}
+	modTime := fileModTime(r.configFile)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			current := fileModTime(r.configFile)
+			if current.Equal(modTime) {
+				continue
+			}
+			modTime = current
+			log.Infof("config file '%s' changed, reloading configuration", r.configFile)
+			if err := r.Reload(); err != nil {
+				log.WithError(err).Error("could not reload configuration, keeping the previous one")
+			}
+		}
+	}()
+}
+
+func fileModTime(path string) time.Time {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}
+	}
+	return info.ModTime()
+}
@@ -0,0 +1,82 @@
+package reload
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/cmd/webhook/init/configuration"
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/cmd/webhook/init/dnsprovider"
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/pkg/webhook"
+)
+
+func writeConfig(t *testing.T, path, domain string) {
+	config := fmt.Sprintf(`
+DOMAIN_FILTER: %s
+INFOBLOX_WAPI_USER: user123
+INFOBLOX_WAPI_PASSWORD: password
+INFOBLOX_VERSION: 2.7.1
+INFOBLOX_STARTUP_CHECK: false
+`, domain)
+	assert.NoError(t, os.WriteFile(path, []byte(config), 0600))
+}
+
+func domainFilter(r *Reloader) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.config.DomainFilter
+}
+
+func TestReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	writeConfig(t, path, "first.com")
+	config := configuration.Init(path)
+	provider, err := dnsprovider.Init(config)
+	assert.NoError(t, err)
+	wh := webhook.New(provider)
+	reloader := New(path, config, wh)
+
+	writeConfig(t, path, "second.com")
+	assert.NoError(t, reloader.Reload())
+	assert.Equal(t, []string{"second.com"}, domainFilter(reloader))
+
+	// an invalid configuration keeps the previous provider
+	assert.NoError(t, os.WriteFile(path, []byte(`INFOBLOX_UNKNOWN: true`), 0600))
+	assert.ErrorContains(t, reloader.Reload(), "unknown key 'INFOBLOX_UNKNOWN'")
+	assert.Equal(t, []string{"second.com"}, domainFilter(reloader))
+
+	reloader.WatchConfigFile(10 * time.Millisecond)
+	writeConfig(t, path, "third.com")
+	assert.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	assert.Eventually(t, func() bool {
+		return assert.ObjectsAreEqual([]string{"third.com"}, domainFilter(reloader))
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestServerSettingsChanged(t *testing.T) {
+	previous := configuration.Config{ServerPort: 8888, DomainFilter: []string{"first.com"}}
+	assert.False(t, serverSettingsChanged(previous, configuration.Config{ServerPort: 8888, DomainFilter: []string{"second.com"}}))
+	assert.True(t, serverSettingsChanged(previous, configuration.Config{ServerPort: 9999, DomainFilter: []string{"first.com"}}))
+}
@@ -39,28 +39,87 @@ import (
 
 // Init server initialization function
 // The server will respond to the following endpoints:
+// - /metrics (GET): Prometheus metrics, authenticated like the webhook API
 // - / (GET): initialization, negotiates headers and returns the domain filter
 // - /records (GET): returns the current records
 // - /records (POST): applies the changes
+// - /records/plan (POST): returns the operations which would apply the changes
 // - /adjustendpoints (POST): executes the AdjustEndpoints method
+// - /snapshots (GET, admin): lists the snapshots taken before applying changes
+// - /snapshots/{id}/rollback (POST, admin): reverts the changes stored in the snapshot
+// - /approvals (GET, admin): lists the changes waiting for approval
+// - /approvals/{id}/approve (POST, admin): applies the pending change
+// - /approvals/{id}/reject (POST, admin): discards the pending change
 func Init(config configuration.Config, p *webhook.Webhook) *http.Server {
 	r := chi.NewRouter()
 	r.Use(webhook.Health)
-	r.Get("/", p.Negotiate)
-	r.Get("/records", p.Records)
-	r.Post("/records", p.ApplyChanges)
-	r.Post("/adjustendpoints", p.AdjustEndpoints)
+	r.Group(func(r chi.Router) {
+		if config.ServerAuthTokenFile != "" || config.ServerTLSClientCAFile != "" {
+			log.Info("authenticating callers of the webhook API")
+			r.Use(webhook.NewAuthenticator(config.ServerAuthTokenFile, config.ServerTLSClientCAFile != "", config.ServerTLSClientNames).Middleware)
+		}
+		r.Method(http.MethodGet, "/metrics", webhook.MetricsHandler())
+		r.Get("/", p.Negotiate)
+		r.Get("/records", p.Records)
+		r.Post("/records", p.ApplyChanges)
+		r.Post("/records/plan", p.Plan)
+		r.Post("/adjustendpoints", p.AdjustEndpoints)
+	})
+	// the admin endpoints don't accept the credentials of external-dns, so it can't approve its own changes
+	r.Group(func(r chi.Router) {
+		r.Use(webhook.NewAdminAuthenticator(config.ServerAdminTokenFile, config.ServerAdminClientNames).Middleware)
+		r.Get("/snapshots", p.Snapshots)
+		r.Post("/snapshots/{id}/rollback", p.Rollback)
+		r.Get("/approvals", p.PendingChanges)
+		r.Post("/approvals/{id}/approve", p.Approve)
+		r.Post("/approvals/{id}/reject", p.Reject)
+	})
 
 	srv := createHTTPServer(fmt.Sprintf("%s:%d", config.ServerHost, config.ServerPort), r, config.ServerReadTimeout, config.ServerWriteTimeout)
+	tlsConfig, err := createTLSConfig(config)
+	if err != nil {
+		log.Fatalf("invalid TLS configuration: %v", err)
+	}
+	srv.TLSConfig = tlsConfig
 	go func() {
+		var err error
 		log.Infof("starting server on addr: '%s' ", srv.Addr)
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		if srv.TLSConfig != nil {
+			// the certificate is served by the GetCertificate function of the TLS configuration
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Errorf("can't serve on addr: '%s', error: %v", srv.Addr, err)
 		}
 	}()
+
+	if config.ServerHealthPort != 0 {
+		startHealthServer(config, srv)
+	}
 	return srv
 }
 
+// startHealthServer serves the health check and the metrics over plain HTTP on a separate port,
+// for probes which can't present the certificates or tokens required by the webhook API.
+// The health server is closed when srv shuts down.
+func startHealthServer(config configuration.Config, srv *http.Server) {
+	healthSrv := createHTTPServer(fmt.Sprintf("%s:%d", config.ServerHost, config.ServerHealthPort),
+		webhook.Health(webhook.Metrics(http.NotFoundHandler())), config.ServerReadTimeout, config.ServerWriteTimeout)
+	srv.RegisterOnShutdown(func() {
+		if err := healthSrv.Close(); err != nil {
+			log.Errorf("error closing health server: %v", err)
+		}
+	})
+	go func() {
+		log.Infof("starting health server on addr: '%s' ", healthSrv.Addr)
+		if err := healthSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("can't serve health check on addr: '%s', error: %v", healthSrv.Addr, err)
+		}
+	}()
+}
+
 func createHTTPServer(addr string, hand http.Handler, readTimeout, writeTimeout time.Duration) *http.Server {
 	return &http.Server{
 		ReadTimeout:  readTimeout,
@@ -70,11 +129,16 @@ func createHTTPServer(addr string, hand http.Handler, readTimeout, writeTimeout
 	}
 }
 
-// ShutdownGracefully gracefully shutdown the http server
-func ShutdownGracefully(srv *http.Server) {
+// ShutdownGracefully gracefully shutdown the http server, on SIGHUP the reload function is called instead
+func ShutdownGracefully(srv *http.Server, reload func()) {
 	sigCh := make(chan os.Signal, 1)
 	signal.Notify(sigCh, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 	sig := <-sigCh
+	for sig == syscall.SIGHUP && reload != nil {
+		log.Info("reloading configuration due to received signal: SIGHUP")
+		reload()
+		sig = <-sigCh
+	}
 	log.Infof("shutting down server due to received signal: %v", sig)
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	if err := srv.Shutdown(ctx); err != nil {
@@ -20,15 +20,27 @@ Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
 
 import (
 	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
 	"fmt"
 	"io"
+	"math/big"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/AbsaOSS/external-dns-infoblox-webhook/cmd/webhook/init/configuration"
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/internal/infoblox"
 	"github.com/AbsaOSS/external-dns-infoblox-webhook/pkg/webhook"
 	log "github.com/sirupsen/logrus"
 	"sigs.k8s.io/external-dns/endpoint"
@@ -50,16 +62,34 @@ type testCase struct {
 	expectedBody              string
 	expectedChanges           *plan.Changes
 	expectedEndpointsToAdjust []*endpoint.Endpoint
+	returnSnapshots           []webhook.SnapshotInfo
+	returnOperations          []webhook.Operation
+	returnPendingChanges      []webhook.PendingChange
+	expectedID                string
 	log.Ext1FieldLogger
 }
 
 var mockProvider *MockProvider
 
+// adminToken authenticates the requests of the tests to the admin endpoints
+const adminToken = "admin-secret"
+
 func TestMain(m *testing.M) {
 	mockProvider = &MockProvider{}
 
-	srv := Init(configuration.Init(), webhook.New(mockProvider))
-	go ShutdownGracefully(srv)
+	dir, err := os.MkdirTemp("", "server-test")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+	adminTokenFile := filepath.Join(dir, "admin-token")
+	if err = os.WriteFile(adminTokenFile, []byte(adminToken), 0o600); err != nil {
+		panic(err)
+	}
+	config := configuration.Init("")
+	config.ServerAdminTokenFile = adminTokenFile
+	srv := Init(config, webhook.New(mockProvider))
+	go ShutdownGracefully(srv, nil)
 
 	time.Sleep(300 * time.Millisecond)
 
@@ -126,6 +156,28 @@ func TestRecords(t *testing.T) {
 			path:               "/records",
 			body:               "",
 			expectedStatusCode: http.StatusInternalServerError,
+			expectedResponseHeaders: map[string]string{
+				"Content-Type": "application/json",
+			},
+			expectedBody: `{"code":"InternalError","message":"backend error"}`,
+		},
+		{
+			name: "upstream error",
+			hasError: &infoblox.RecordError{
+				Kind:       infoblox.ErrorKindUpstream,
+				RecordType: "A",
+				Zone:       "example.com",
+				Err:        fmt.Errorf("connection refused"),
+			},
+			method:             http.MethodGet,
+			headers:            map[string]string{"Accept": "application/external.dns.webhook+json;version=1"},
+			path:               "/records",
+			body:               "",
+			expectedStatusCode: http.StatusBadGateway,
+			expectedResponseHeaders: map[string]string{
+				"Content-Type": "application/json",
+			},
+			expectedBody: `{"code":"UpstreamError","message":"could not fetch A records in zone 'example.com': connection refused","recordType":"A","zone":"example.com"}`,
 		},
 	}
 
@@ -304,6 +356,71 @@ func TestApplyChanges(t *testing.T) {
 }`,
 			expectedStatusCode: http.StatusInternalServerError,
 		},
+		{
+			name: "conflict error",
+			hasError: &infoblox.RecordError{
+				Kind:       infoblox.ErrorKindConflict,
+				Action:     "CREATE",
+				Name:       "test.example.com",
+				RecordType: "CNAME",
+				Zone:       "example.com",
+				Err: fmt.Errorf("WAPI request error: 400('400 Bad Request')\nContents:\n%s\n",
+					`{"Error": "AdmConDataError: None (IBDataConflictError: IB.Data.Conflict:The record 'test.example.com' already exists.)", "code": "Client.Ibap.Data.Conflict", "text": "The record 'test.example.com' already exists."}`),
+			},
+			method: http.MethodPost,
+			headers: map[string]string{
+				"Content-Type": "application/external.dns.webhook+json;version=1",
+			},
+			path:               "/records",
+			body:               `{"Create": [{"dnsName": "test.example.com", "targets": ["other.example.com"], "recordType": "CNAME"}]}`,
+			expectedStatusCode: http.StatusConflict,
+			expectedResponseHeaders: map[string]string{
+				"Content-Type": "application/json",
+			},
+			expectedBody: `{"code":"Conflict","message":"could not create CNAME record 'test.example.com' in zone 'example.com': WAPI request error: 400('400 Bad Request')\nContents:\n{\"Error\": \"AdmConDataError: None (IBDataConflictError: IB.Data.Conflict:The record 'test.example.com' already exists.)\", \"code\": \"Client.Ibap.Data.Conflict\", \"text\": \"The record 'test.example.com' already exists.\"}\n","record":"test.example.com","recordType":"CNAME","zone":"example.com","wapiError":"The record 'test.example.com' already exists."}`,
+		},
+		{
+			name: "invalid change",
+			hasError: &infoblox.RecordError{
+				Kind:       infoblox.ErrorKindInvalid,
+				Action:     "CREATE",
+				Name:       "test..example.com",
+				RecordType: "A",
+				Zone:       "example.com",
+				Err: fmt.Errorf("WAPI request error: 400('400 Bad Request')\nContents:\n%s\n",
+					`{"Error": "AdmConProtoError: Invalid value for name", "code": "Client.Ibap.Proto", "text": "Invalid value for name"}`),
+			},
+			method: http.MethodPost,
+			headers: map[string]string{
+				"Content-Type": "application/external.dns.webhook+json;version=1",
+			},
+			path:               "/records",
+			body:               `{"Create": [{"dnsName": "test..example.com", "targets": ["1.1.1.1"], "recordType": "A"}]}`,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedResponseHeaders: map[string]string{
+				"Content-Type": "application/json",
+			},
+			expectedBody: `{"code":"InvalidChange","message":"could not create A record 'test..example.com' in zone 'example.com': WAPI request error: 400('400 Bad Request')\nContents:\n{\"Error\": \"AdmConProtoError: Invalid value for name\", \"code\": \"Client.Ibap.Proto\", \"text\": \"Invalid value for name\"}\n","record":"test..example.com","recordType":"A","zone":"example.com","wapiError":"Invalid value for name"}`,
+		},
+		{
+			name: "partially failed changes",
+			hasError: infoblox.NewApplyError([]infoblox.ChangeResult{
+				{Action: "CREATE", Name: "a.example.com", RecordType: "A", Target: "1.1.1.1", Zone: "example.com", Status: infoblox.ChangeStatusApplied},
+				{Action: "CREATE", Name: "b.example.com", RecordType: "MX", Target: "10 mx.example.com", Zone: "example.com", Status: infoblox.ChangeStatusFailed,
+					Err: &infoblox.RecordError{Kind: infoblox.ErrorKindUnsupported, Action: "CREATE", Name: "b.example.com", RecordType: "MX", Zone: "example.com", Err: fmt.Errorf("record type 'MX' is not supported")}},
+			}),
+			method: http.MethodPost,
+			headers: map[string]string{
+				"Content-Type": "application/external.dns.webhook+json;version=1",
+			},
+			path:               "/records",
+			body:               `{"Create": [{"dnsName": "a.example.com", "targets": ["1.1.1.1"], "recordType": "A"}]}`,
+			expectedStatusCode: http.StatusUnprocessableEntity,
+			expectedResponseHeaders: map[string]string{
+				"Content-Type": "application/json",
+			},
+			expectedBody: `{"code":"UnsupportedRecordType","message":"could not create MX record 'b.example.com' in zone 'example.com': record type 'MX' is not supported","record":"b.example.com","recordType":"MX","zone":"example.com","changes":[{"action":"CREATE","record":"a.example.com","recordType":"A","target":"1.1.1.1","zone":"example.com","status":"applied"},{"action":"CREATE","record":"b.example.com","recordType":"MX","target":"10 mx.example.com","zone":"example.com","status":"failed","error":"could not create MX record 'b.example.com' in zone 'example.com': record type 'MX' is not supported"}]}`,
+		},
 	}
 
 	executeTestCases(t, testCases)
@@ -483,6 +600,472 @@ func TestNegotiate(t *testing.T) {
 	executeTestCases(t, testCases)
 }
 
+func TestPlan(t *testing.T) {
+	testCases := []testCase{
+		{
+			name:   "valid case",
+			method: http.MethodPost,
+			headers: map[string]string{
+				"Content-Type": "application/external.dns.webhook+json;version=1",
+			},
+			path: "/records/plan",
+			body: `{"Create":[{"dnsName":"test.example.com","targets":["11.11.11.11"],"recordType":"A","recordTTL":3600}]}`,
+			expectedChanges: &plan.Changes{
+				Create: []*endpoint.Endpoint{
+					{
+						DNSName:    "test.example.com",
+						Targets:    []string{"11.11.11.11"},
+						RecordType: "A",
+						RecordTTL:  3600,
+					},
+				},
+			},
+			returnOperations: []webhook.Operation{
+				{Action: "CREATE", Object: "record:a", Name: "test.example.com", RecordType: "A", Target: "11.11.11.11", TTL: 3600, Zone: "example.com"},
+			},
+			expectedStatusCode: http.StatusOK,
+			expectedResponseHeaders: map[string]string{
+				"Content-Type": "application/json",
+			},
+			expectedBody: `[{"action":"CREATE","object":"record:a","name":"test.example.com","recordType":"A","target":"11.11.11.11","ttl":3600,"zone":"example.com"}]`,
+		},
+		{
+			name:   "plan rejected",
+			method: http.MethodPost,
+			headers: map[string]string{
+				"Content-Type": "application/external.dns.webhook+json;version=1",
+			},
+			path: "/records/plan",
+			body: `{}`,
+			hasError: &infoblox.RecordError{
+				Kind: infoblox.ErrorKindRejected,
+				Zone: "example.com",
+				Err:  fmt.Errorf("refusing to delete 3 records in zone 'example.com'"),
+			},
+			expectedStatusCode: http.StatusForbidden,
+			expectedBody:       `{"code":"PlanRejected","message":"refusing to delete 3 records in zone 'example.com'","zone":"example.com"}`,
+		},
+		{
+			name:               "no content type header",
+			method:             http.MethodPost,
+			headers:            map[string]string{},
+			path:               "/records/plan",
+			body:               `{}`,
+			expectedStatusCode: http.StatusNotAcceptable,
+			expectedBody:       "client must provide a content type",
+		},
+	}
+
+	executeTestCases(t, testCases)
+}
+
+func TestSnapshots(t *testing.T) {
+	testCases := []testCase{
+		{
+			name: "list snapshots",
+			returnSnapshots: []webhook.SnapshotInfo{
+				{ID: "20240603T101542.000000000Z", Created: time.Date(2024, 6, 3, 10, 15, 42, 0, time.UTC), Changes: 3},
+			},
+			method:             http.MethodGet,
+			headers:            map[string]string{"Authorization": "Bearer " + adminToken},
+			path:               "/snapshots",
+			expectedStatusCode: http.StatusOK,
+			expectedResponseHeaders: map[string]string{
+				"Content-Type": "application/json",
+			},
+			expectedBody: `[{"id":"20240603T101542.000000000Z","created":"2024-06-03T10:15:42Z","changes":3}]`,
+		},
+		{
+			name:               "snapshots disabled",
+			hasError:           infoblox.ErrSnapshotsDisabled,
+			method:             http.MethodGet,
+			headers:            map[string]string{"Authorization": "Bearer " + adminToken},
+			path:               "/snapshots",
+			expectedStatusCode: http.StatusNotImplemented,
+			expectedBody:       `{"code":"SnapshotsDisabled","message":"snapshots are disabled, set INFOBLOX_SNAPSHOT_DIR to enable them"}`,
+		},
+		{
+			name:               "rollback",
+			expectedID:         "20240603T101542.000000000Z",
+			method:             http.MethodPost,
+			headers:            map[string]string{"Authorization": "Bearer " + adminToken},
+			path:               "/snapshots/20240603T101542.000000000Z/rollback",
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "rollback of unknown snapshot",
+			hasError:           fmt.Errorf("%w: 'unknown'", infoblox.ErrSnapshotNotFound),
+			expectedID:         "unknown",
+			method:             http.MethodPost,
+			headers:            map[string]string{"Authorization": "Bearer " + adminToken},
+			path:               "/snapshots/unknown/rollback",
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       `{"code":"SnapshotNotFound","message":"snapshot not found: 'unknown'"}`,
+		},
+		{
+			name:               "rollback without admin token",
+			method:             http.MethodPost,
+			path:               "/snapshots/20240603T101542.000000000Z/rollback",
+			expectedStatusCode: http.StatusForbidden,
+			expectedBody:       `{"code":"Forbidden","message":"forbidden: invalid bearer token"}`,
+		},
+	}
+
+	executeTestCases(t, testCases)
+}
+
+func TestApprovals(t *testing.T) {
+	testCases := []testCase{
+		{
+			name: "list pending changes",
+			returnPendingChanges: []webhook.PendingChange{
+				{
+					ID:       "0123456789abcdef",
+					Zone:     "example.com",
+					Action:   "CREATE",
+					Endpoint: endpoint.NewEndpoint("test.example.com", "A", "1.1.1.1"),
+					Created:  time.Date(2024, 6, 3, 10, 0, 0, 0, time.UTC),
+					Expires:  time.Date(2024, 6, 4, 10, 0, 0, 0, time.UTC),
+				},
+			},
+			method:             http.MethodGet,
+			headers:            map[string]string{"Authorization": "Bearer " + adminToken},
+			path:               "/approvals",
+			expectedStatusCode: http.StatusOK,
+			expectedResponseHeaders: map[string]string{
+				"Content-Type": "application/json",
+			},
+			expectedBody: `[{"id":"0123456789abcdef","zone":"example.com","action":"CREATE","endpoint":{"dnsName":"test.example.com","targets":["1.1.1.1"],"recordType":"A"},"operation":{"action":"","object":"","name":"","recordType":"","target":"","ttl":0,"zone":""},"created":"2024-06-03T10:00:00Z","expires":"2024-06-04T10:00:00Z"}]`,
+		},
+		{
+			name:               "approvals disabled",
+			hasError:           infoblox.ErrApprovalsDisabled,
+			method:             http.MethodGet,
+			headers:            map[string]string{"Authorization": "Bearer " + adminToken},
+			path:               "/approvals",
+			expectedStatusCode: http.StatusNotImplemented,
+			expectedBody:       `{"code":"ApprovalsDisabled","message":"approvals are disabled, set INFOBLOX_APPROVAL_ZONES to enable them"}`,
+		},
+		{
+			name:               "approve",
+			expectedID:         "0123456789abcdef",
+			method:             http.MethodPost,
+			headers:            map[string]string{"Authorization": "Bearer " + adminToken},
+			path:               "/approvals/0123456789abcdef/approve",
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "reject",
+			expectedID:         "0123456789abcdef",
+			method:             http.MethodPost,
+			headers:            map[string]string{"Authorization": "Bearer " + adminToken},
+			path:               "/approvals/0123456789abcdef/reject",
+			expectedStatusCode: http.StatusNoContent,
+		},
+		{
+			name:               "approve unknown change",
+			hasError:           fmt.Errorf("%w: 'unknown'", infoblox.ErrPendingChangeNotFound),
+			expectedID:         "unknown",
+			method:             http.MethodPost,
+			headers:            map[string]string{"Authorization": "Bearer " + adminToken},
+			path:               "/approvals/unknown/approve",
+			expectedStatusCode: http.StatusNotFound,
+			expectedBody:       `{"code":"PendingChangeNotFound","message":"pending change not found: 'unknown'"}`,
+		},
+	}
+
+	executeTestCases(t, testCases)
+}
+
+func TestAuthentication(t *testing.T) {
+	dir := t.TempDir()
+	certs := writeTestCertificates(t, dir)
+	tokenFile := filepath.Join(dir, "token")
+	if err := os.WriteFile(tokenFile, []byte("secret\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	config := configuration.Init("")
+	config.ServerHost = "localhost"
+	config.ServerPort = 8889
+	config.ServerTLSCertFile = certs.serverCert
+	config.ServerTLSKeyFile = certs.serverKey
+	config.ServerTLSClientCAFile = certs.ca
+	config.ServerTLSClientNames = []string{"external-dns"}
+	config.ServerAuthTokenFile = tokenFile
+	config.ServerHealthPort = 8890
+	config.ServerAdminClientNames = []string{"dns-admin"}
+	srv := Init(config, webhook.New(mockProvider))
+	defer srv.Shutdown(context.TODO()) // nolint: errcheck
+	time.Sleep(300 * time.Millisecond)
+
+	mockProvider.testCase = testCase{returnRecords: []*endpoint.Endpoint{}, expectedID: "0123456789abcdef"}
+	mockProvider.t = t
+	tests := []struct {
+		name               string
+		method             string
+		path               string
+		clientCert         *tls.Certificate
+		token              string
+		expectedStatusCode int
+	}{
+		{name: "health check is open", path: "/healthz", expectedStatusCode: http.StatusOK},
+		{name: "metrics require authentication", path: "/metrics", clientCert: certs.otherClient, expectedStatusCode: http.StatusUnauthorized},
+		{name: "metrics", path: "/metrics", clientCert: certs.client, token: "secret", expectedStatusCode: http.StatusOK},
+		{name: "no client certificate", path: "/records", token: "secret", expectedStatusCode: http.StatusUnauthorized},
+		{name: "client certificate not allowed", path: "/records", clientCert: certs.otherClient, token: "secret", expectedStatusCode: http.StatusUnauthorized},
+		{name: "no token", path: "/records", clientCert: certs.client, expectedStatusCode: http.StatusUnauthorized},
+		{name: "wrong token", path: "/records", clientCert: certs.client, token: "guess", expectedStatusCode: http.StatusUnauthorized},
+		{name: "authenticated", path: "/records", clientCert: certs.client, token: "secret", expectedStatusCode: http.StatusOK},
+		{name: "external-dns can't approve", method: http.MethodPost, path: "/approvals/0123456789abcdef/approve", clientCert: certs.client, token: "secret", expectedStatusCode: http.StatusForbidden},
+		{name: "external-dns can't roll back", method: http.MethodPost, path: "/snapshots/20240603T101542.000000000Z/rollback", clientCert: certs.client, token: "secret", expectedStatusCode: http.StatusForbidden},
+		{name: "admin approves", method: http.MethodPost, path: "/approvals/0123456789abcdef/approve", clientCert: certs.admin, expectedStatusCode: http.StatusNoContent},
+		{name: "admin can't apply changes", path: "/records", clientCert: certs.admin, expectedStatusCode: http.StatusUnauthorized},
+	}
+	for _, path := range []string{"/healthz", "/metrics"} {
+		t.Run("plain HTTP health port "+path, func(t *testing.T) {
+			response, err := http.Get("http://localhost:8890" + path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = response.Body.Close()
+			if response.StatusCode != http.StatusOK {
+				t.Errorf("expected status code %d, got %d", http.StatusOK, response.StatusCode)
+			}
+		})
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tlsConfig := &tls.Config{RootCAs: certs.pool}
+			if tc.clientCert != nil {
+				tlsConfig.Certificates = []tls.Certificate{*tc.clientCert}
+			}
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			request, _ := http.NewRequest(method, "https://localhost:8889"+tc.path, nil)
+			request.Header.Set("Accept", "application/external.dns.webhook+json;version=1")
+			if tc.token != "" {
+				request.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			response, err := client.Do(request)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_ = response.Body.Close()
+			if response.StatusCode != tc.expectedStatusCode {
+				t.Errorf("expected status code %d, got %d", tc.expectedStatusCode, response.StatusCode)
+			}
+		})
+	}
+}
+
+func TestCreateTLSConfigClientAuth(t *testing.T) {
+	certs := writeTestCertificates(t, t.TempDir())
+	cases := []struct {
+		name         string
+		clientNames  []string
+		expectedAuth tls.ClientAuthType
+	}{
+		{name: "any client signed by the CA", expectedAuth: tls.VerifyClientCertIfGiven},
+		// the names are checked by the Authenticator, so the health check stays open
+		{name: "client names", clientNames: []string{"external-dns"}, expectedAuth: tls.VerifyClientCertIfGiven},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tlsConfig, err := createTLSConfig(configuration.Config{
+				ServerTLSCertFile:     certs.serverCert,
+				ServerTLSKeyFile:      certs.serverKey,
+				ServerTLSMinVersion:   "1.2",
+				ServerTLSClientCAFile: certs.ca,
+				ServerTLSClientNames:  tc.clientNames,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tlsConfig.ClientAuth != tc.expectedAuth {
+				t.Errorf("expected client authentication %v, got %v", tc.expectedAuth, tlsConfig.ClientAuth)
+			}
+		})
+	}
+}
+
+func TestCreateTLSConfig(t *testing.T) {
+	certs := writeTestCertificates(t, t.TempDir())
+	cases := []struct {
+		name          string
+		minVersion    string
+		cipherSuites  []string
+		expectedError string
+	}{
+		{name: "defaults", minVersion: "1.2"},
+		{name: "TLS 1.3", minVersion: "1.3"},
+		{name: "cipher suites", minVersion: "1.2", cipherSuites: []string{"TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384"}},
+		{name: "unsupported version", minVersion: "1.0", expectedError: "unsupported SERVER_TLS_MIN_VERSION '1.0', expected 1.2 or 1.3"},
+		{name: "insecure cipher suite", minVersion: "1.2", cipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}, expectedError: "unsupported cipher suite 'TLS_RSA_WITH_RC4_128_SHA' in SERVER_TLS_CIPHER_SUITES"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tlsConfig, err := createTLSConfig(configuration.Config{
+				ServerTLSCertFile:     certs.serverCert,
+				ServerTLSKeyFile:      certs.serverKey,
+				ServerTLSMinVersion:   tc.minVersion,
+				ServerTLSCipherSuites: tc.cipherSuites,
+			})
+			if tc.expectedError != "" {
+				if err == nil || err.Error() != tc.expectedError {
+					t.Errorf("expected error '%s', got '%v'", tc.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tlsConfig.MinVersion != tlsVersions[tc.minVersion] || len(tlsConfig.CipherSuites) != len(tc.cipherSuites) {
+				t.Errorf("unexpected TLS configuration: %+v", tlsConfig)
+			}
+		})
+	}
+}
+
+func TestCertificateReloader(t *testing.T) {
+	dir := t.TempDir()
+	first := writeTestCertificates(t, filepath.Join(dir, "first"))
+	second := writeTestCertificates(t, filepath.Join(dir, "second"))
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	install := func(certs testCertificates, modTime time.Time) {
+		for src, dst := range map[string]string{certs.serverCert: certFile, certs.serverKey: keyFile} {
+			data, err := os.ReadFile(src)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = os.WriteFile(dst, data, 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if err = os.Chtimes(dst, modTime, modTime); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	serial := func(cert *tls.Certificate) string {
+		parsed, err := x509.ParseCertificate(cert.Certificate[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed.SerialNumber.String()
+	}
+
+	install(first, time.Now().Add(-time.Minute))
+	reloader, err := newCertificateReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, _ := reloader.GetCertificate(nil)
+	initial := serial(cert)
+
+	// a half written rotation keeps the previous certificate
+	if err = os.WriteFile(keyFile, []byte("garbage"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cert, _ = reloader.GetCertificate(nil)
+	if serial(cert) != initial {
+		t.Error("expected the previous certificate while the key pair is invalid")
+	}
+
+	install(second, time.Now())
+	cert, _ = reloader.GetCertificate(nil)
+	if serial(cert) == initial {
+		t.Error("expected the rotated certificate")
+	}
+}
+
+type testCertificates struct {
+	ca, serverCert, serverKey  string
+	pool                       *x509.CertPool
+	client, otherClient, admin *tls.Certificate
+}
+
+// writeTestCertificates creates a CA with a server certificate for localhost and three client certificates
+func writeTestCertificates(t *testing.T, dir string) testCertificates {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+	newKey := func() *ecdsa.PrivateKey {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return key
+	}
+	newCert := func(template, parent *x509.Certificate, key *ecdsa.PrivateKey, parentKey *ecdsa.PrivateKey) (*x509.Certificate, []byte) {
+		template.SerialNumber = big.NewInt(time.Now().UnixNano())
+		template.NotBefore = time.Now().Add(-time.Hour)
+		template.NotAfter = time.Now().Add(time.Hour)
+		der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cert, _ := x509.ParseCertificate(der)
+		return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	}
+	keyPEM := func(key *ecdsa.PrivateKey) []byte {
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der})
+	}
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	clientCert := func(name string, ca *x509.Certificate, caKey *ecdsa.PrivateKey) *tls.Certificate {
+		key := newKey()
+		_, certPEM := newCert(&x509.Certificate{
+			Subject:     pkix.Name{CommonName: name},
+			ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		}, ca, key, caKey)
+		cert, err := tls.X509KeyPair(certPEM, keyPEM(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &cert
+	}
+
+	caKey := newKey()
+	caTemplate := &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "test CA"},
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	ca, caPEM := newCert(caTemplate, caTemplate, caKey, caKey)
+	serverKey := newKey()
+	_, serverPEM := newCert(&x509.Certificate{
+		Subject:     pkix.Name{CommonName: "localhost"},
+		DNSNames:    []string{"localhost"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca, serverKey, caKey)
+
+	certs := testCertificates{
+		ca:          write("ca.pem", caPEM),
+		serverCert:  write("server.pem", serverPEM),
+		serverKey:   write("server-key.pem", keyPEM(serverKey)),
+		pool:        x509.NewCertPool(),
+		client:      clientCert("external-dns", ca, caKey),
+		otherClient: clientCert("intruder", ca, caKey),
+		admin:       clientCert("dns-admin", ca, caKey),
+	}
+	certs.pool.AddCert(ca)
+	return certs
+}
+
 func executeTestCases(t *testing.T, testCases []testCase) {
 	log.SetLevel(log.DebugLevel)
 
@@ -532,6 +1115,44 @@ func executeTestCases(t *testing.T, testCases []testCase) {
 	}
 }
 
+// closingProvider records that it was closed after it was replaced
+type closingProvider struct {
+	MockProvider
+	closed chan struct{}
+}
+
+func (p *closingProvider) Close() error {
+	close(p.closed)
+	return nil
+}
+
+func TestSetProvider(t *testing.T) {
+	first := &closingProvider{closed: make(chan struct{})}
+	first.testCase.returnDomainFilter = endpoint.NewDomainFilter([]string{"first.com"})
+	second := &MockProvider{testCase: testCase{returnDomainFilter: endpoint.NewDomainFilter([]string{"second.com"})}}
+	wh := webhook.New(first)
+	negotiate := func() string {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept", "application/external.dns.webhook+json;version=1")
+		rec := httptest.NewRecorder()
+		wh.Negotiate(rec, req)
+		return rec.Body.String()
+	}
+
+	if body := negotiate(); !strings.Contains(body, "first.com") {
+		t.Errorf("expected the domain filter of the first provider, got '%s'", body)
+	}
+	wh.SetProvider(second)
+	if body := negotiate(); !strings.Contains(body, "second.com") {
+		t.Errorf("expected the domain filter of the second provider, got '%s'", body)
+	}
+	select {
+	case <-first.closed:
+	case <-time.After(time.Second):
+		t.Error("the replaced provider was not closed")
+	}
+}
+
 type MockProvider struct {
 	t        *testing.T
 	testCase testCase
@@ -561,3 +1182,42 @@ func (d *MockProvider) AdjustEndpoints(endpoints []*endpoint.Endpoint) ([]*endpo
 func (d *MockProvider) GetDomainFilter() endpoint.DomainFilter {
 	return d.testCase.returnDomainFilter
 }
+
+func (d *MockProvider) Snapshots() ([]webhook.SnapshotInfo, error) {
+	return d.testCase.returnSnapshots, d.testCase.hasError
+}
+
+func (d *MockProvider) Rollback(_ context.Context, id string) error {
+	if id != d.testCase.expectedID {
+		d.t.Errorf("expected snapshot '%s', got '%s'", d.testCase.expectedID, id)
+	}
+	return d.testCase.hasError
+}
+
+func (d *MockProvider) Plan(_ context.Context, changes *plan.Changes) ([]webhook.Operation, error) {
+	if d.testCase.hasError != nil {
+		return nil, d.testCase.hasError
+	}
+	if !reflect.DeepEqual(changes, d.testCase.expectedChanges) {
+		d.t.Errorf("expected changes '%v', got '%v'", d.testCase.expectedChanges, changes)
+	}
+	return d.testCase.returnOperations, nil
+}
+
+func (d *MockProvider) PendingChanges() ([]webhook.PendingChange, error) {
+	return d.testCase.returnPendingChanges, d.testCase.hasError
+}
+
+func (d *MockProvider) Approve(_ context.Context, id string) error {
+	if id != d.testCase.expectedID {
+		d.t.Errorf("expected pending change '%s', got '%s'", d.testCase.expectedID, id)
+	}
+	return d.testCase.hasError
+}
+
+func (d *MockProvider) Reject(id string) error {
+	if id != d.testCase.expectedID {
+		d.t.Errorf("expected pending change '%s', got '%s'", d.testCase.expectedID, id)
+	}
+	return d.testCase.hasError
+}
@@ -0,0 +1,163 @@
+package server
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/cmd/webhook/init/configuration"
+)
+
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// createTLSConfig returns the TLS configuration of the server or nil to serve plain HTTP.
+// Client certificates are verified if given, so the health check works without them;
+// the Authenticator requires them on the API and checks SERVER_TLS_CLIENT_NAMES.
+func createTLSConfig(config configuration.Config) (*tls.Config, error) {
+	if len(config.ServerAdminClientNames) > 0 && config.ServerTLSClientCAFile == "" {
+		return nil, errors.New("SERVER_ADMIN_CLIENT_NAMES requires SERVER_TLS_CLIENT_CA_FILE")
+	}
+	if config.ServerTLSCertFile == "" && config.ServerTLSKeyFile == "" {
+		if config.ServerTLSClientCAFile != "" {
+			return nil, errors.New("SERVER_TLS_CLIENT_CA_FILE requires SERVER_TLS_CERT_FILE and SERVER_TLS_KEY_FILE")
+		}
+		return nil, nil
+	}
+	if config.ServerTLSCertFile == "" || config.ServerTLSKeyFile == "" {
+		return nil, errors.New("both SERVER_TLS_CERT_FILE and SERVER_TLS_KEY_FILE must be set")
+	}
+
+	minVersion, ok := tlsVersions[config.ServerTLSMinVersion]
+	if !ok {
+		return nil, fmt.Errorf("unsupported SERVER_TLS_MIN_VERSION '%s', expected 1.2 or 1.3", config.ServerTLSMinVersion)
+	}
+	cipherSuites, err := parseCipherSuites(config.ServerTLSCipherSuites)
+	if err != nil {
+		return nil, err
+	}
+	reloader, err := newCertificateReloader(config.ServerTLSCertFile, config.ServerTLSKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		GetCertificate: reloader.GetCertificate,
+	}
+
+	if config.ServerTLSClientCAFile != "" {
+		pem, err := os.ReadFile(config.ServerTLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read client CA: %w", err)
+		}
+		tlsConfig.ClientCAs = x509.NewCertPool()
+		if !tlsConfig.ClientCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA '%s'", config.ServerTLSClientCAFile)
+		}
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
+}
+
+// parseCipherSuites translates the names of the cipher suites, only secure suites are accepted.
+// The suites apply to TLS 1.2, the suites of TLS 1.3 are not configurable.
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	supported := map[string]uint16{}
+	for _, suite := range tls.CipherSuites() {
+		supported[suite.Name] = suite.ID
+	}
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := supported[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unsupported cipher suite '%s' in SERVER_TLS_CIPHER_SUITES", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// certificateReloader serves the certificate of the key pair files and loads them again when they change,
+// so rotated certificates (e.g. by cert-manager) are used without a restart
+type certificateReloader struct {
+	certFile, keyFile string
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	certMod time.Time
+	keyMod  time.Time
+}
+
+func newCertificateReloader(certFile, keyFile string) (*certificateReloader, error) {
+	r := &certificateReloader{certFile: certFile, keyFile: keyFile}
+	if err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// reload loads the key pair if one of the files was modified since it was loaded last
+func (r *certificateReloader) reload() error {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return fmt.Errorf("could not read TLS certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return fmt.Errorf("could not read TLS key: %w", err)
+	}
+	if r.cert != nil && certInfo.ModTime().Equal(r.certMod) && keyInfo.ModTime().Equal(r.keyMod) {
+		return nil
+	}
+	cert, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+	if err != nil {
+		return fmt.Errorf("could not load TLS key pair: %w", err)
+	}
+	if r.cert != nil {
+		log.Infof("reloaded TLS certificate '%s'", r.certFile)
+	}
+	r.cert, r.certMod, r.keyMod = &cert, certInfo.ModTime(), keyInfo.ModTime()
+	return nil
+}
+
+// GetCertificate returns the current certificate. If the rotated files can't be loaded,
+// e.g. while only one of them is written, the previous certificate is served.
+func (r *certificateReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.reload(); err != nil {
+		log.WithError(err).Warn("serving the previous TLS certificate")
+	}
+	return r.cert, nil
+}
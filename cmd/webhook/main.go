@@ -20,13 +20,13 @@ Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
 */
 
 import (
+	"flag"
 	"fmt"
+	"os"
+	"sort"
+	"strings"
 
-	"github.com/AbsaOSS/external-dns-infoblox-webhook/cmd/webhook/init/configuration"
-	"github.com/AbsaOSS/external-dns-infoblox-webhook/cmd/webhook/init/dnsprovider"
 	"github.com/AbsaOSS/external-dns-infoblox-webhook/cmd/webhook/init/logging"
-	"github.com/AbsaOSS/external-dns-infoblox-webhook/cmd/webhook/init/server"
-	"github.com/AbsaOSS/external-dns-infoblox-webhook/pkg/webhook"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -49,16 +49,78 @@ var (
 )
 
 func main() {
-	fmt.Printf(banner, Version, Gitsha)
+	var opts options
+	flags := newFlagSet(&opts)
+
+	name := "serve"
+	args := os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command '%s'\n", name)
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	_ = flags.Parse(args)
+	opts.args = flags.Args()
+	if opts.output != "table" && opts.output != "json" && opts.output != "yaml" {
+		fmt.Fprintf(os.Stderr, "unknown output format '%s'\n", opts.output)
+		flags.Usage()
+		os.Exit(2)
+	}
 
 	logging.Init()
 
-	config := configuration.Init()
-	provider, err := dnsprovider.Init(config)
-	if err != nil {
-		log.Fatalf("failed to initialize provider: %v", err)
+	if err := cmd.run(opts); err != nil {
+		log.Fatalf("%s failed: %v", name, err)
 	}
+}
 
-	srv := server.Init(config, webhook.New(provider))
-	server.ShutdownGracefully(srv)
+// newFlagSet creates the flags of all commands, parsed into opts
+func newFlagSet(opts *options) *flag.FlagSet {
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags.StringVar(&opts.configFile, "config", os.Getenv("CONFIG_FILE"), "path to the YAML or JSON config file, environment variables override its settings")
+	flags.StringVar(&opts.output, "output", "table", "output format of records, zones, diff and gc: table, json or yaml")
+	flags.StringVar(&opts.dir, "dir", "", "directory export writes the zone files to, stdout if empty")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "import only prints the differences")
+	flags.BoolVar(&opts.sync, "sync", false, "import and diff delete the records which are missing in the files")
+	flags.Var((*listFlag)(&opts.owners), "owners", "comma separated owner IDs of the live external-dns instances, records of other owners are orphaned")
+	flags.BoolVar(&opts.delete, "delete", false, "gc deletes the orphaned records instead of only reporting them")
+	flags.StringVar(&opts.ownerID, "owner-id", "", "import and diff update and delete only the records owned by this ID, like the external-dns --txt-owner-id")
+	flags.StringVar(&opts.txtPrefix, "txt-prefix", "", "prefix of the TXT registry records, like the external-dns --txt-prefix")
+	flags.StringVar(&opts.txtSuffix, "txt-suffix", "", "suffix of the TXT registry records, like the external-dns --txt-suffix")
+	flags.StringVar(&opts.txtWildcardReplacement, "txt-wildcard-replacement", "", "like the external-dns --txt-wildcard-replacement")
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [command] [flags] [files]\n\nCommands:\n", os.Args[0])
+		names := make([]string, 0, len(commands))
+		for name := range commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].description)
+		}
+		fmt.Fprintln(os.Stderr, "\nFlags:")
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// listFlag is a flag of comma separated values
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
 }
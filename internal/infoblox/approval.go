@@ -0,0 +1,362 @@
+package infoblox
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/external-dns/endpoint"
+
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/pkg/webhook"
+)
+
+var (
+	// ErrApprovalsDisabled is returned by approval operations when INFOBLOX_APPROVAL_ZONES is not set
+	ErrApprovalsDisabled error = &statusError{"ApprovalsDisabled", http.StatusNotImplemented,
+		"approvals are disabled, set INFOBLOX_APPROVAL_ZONES to enable them"}
+	// ErrPendingChangeNotFound is returned when the pending change doesn't exist or has expired
+	ErrPendingChangeNotFound error = &statusError{"PendingChangeNotFound", http.StatusNotFound, "pending change not found"}
+)
+
+// pendingChange is a change of a zone requiring approval as stored in the approval queue. Rejected changes
+// stay in the queue until they expire, so external-dns can't queue them again.
+type pendingChange struct {
+	webhook.PendingChange
+	// Hash identifies the content of the change, external-dns sends the same content on every sync
+	Hash     string `json:"hash,omitempty"`
+	Rejected bool   `json:"rejected,omitempty"`
+	// Settings are restored with the change, set for the changes of a rollback
+	Settings *RecordSettings `json:"settings,omitempty"`
+}
+
+// changeHash returns the hash of the content of the change
+func changeHash(zone, action string, ep *endpoint.Endpoint) string {
+	targets := slices.Clone(ep.Targets)
+	slices.Sort(targets)
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\n%s\n%s\n%s\n%d\n%s", strings.ToLower(zone), action, ep.DNSName, ep.RecordType,
+		ep.RecordTTL, strings.Join(targets, "\n"))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// approvalQueue persists the pending changes in a JSON file
+type approvalQueue struct {
+	mu   sync.Mutex
+	path string
+}
+
+var (
+	approvalQueuesMu sync.Mutex
+	approvalQueues   = map[string]*approvalQueue{}
+)
+
+// approvalQueueFor returns the queue of the file. The providers created on configuration reloads share the file,
+// so they share the queue and its lock.
+func approvalQueueFor(path string) *approvalQueue {
+	approvalQueuesMu.Lock()
+	defer approvalQueuesMu.Unlock()
+	path = filepath.Clean(path)
+	q, ok := approvalQueues[path]
+	if !ok {
+		q = &approvalQueue{path: path}
+		approvalQueues[path] = q
+	}
+	return q
+}
+
+// load reads the pending changes and drops the expired ones
+func (q *approvalQueue) load() ([]pendingChange, error) {
+	data, err := os.ReadFile(q.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return []pendingChange{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read approval queue: %w", err)
+	}
+	var pending []pendingChange
+	if err = json.Unmarshal(data, &pending); err != nil {
+		return nil, fmt.Errorf("could not parse approval queue '%s': %w", q.path, err)
+	}
+	for i := range pending {
+		if pending[i].Hash == "" {
+			pending[i].Hash = changeHash(pending[i].Zone, pending[i].Action, pending[i].Endpoint)
+		}
+	}
+	now := time.Now()
+	return slices.DeleteFunc(pending, func(c pendingChange) bool {
+		if !now.After(c.Expires) {
+			return false
+		}
+		if !c.Rejected {
+			log.Warnf("pending %s of %s record '%s' in zone '%s' expired without approval",
+				c.Action, c.Endpoint.RecordType, c.Endpoint.DNSName, c.Zone)
+		}
+		return true
+	}), nil
+}
+
+// find returns the index of the pending change which isn't rejected
+func find(pending []pendingChange, id string) (int, error) {
+	i := slices.IndexFunc(pending, func(c pendingChange) bool { return c.ID == id && !c.Rejected })
+	if i < 0 {
+		return -1, fmt.Errorf("%w: '%s'", ErrPendingChangeNotFound, id)
+	}
+	return i, nil
+}
+
+// save replaces the queue file, so it is never left partially written
+func (q *approvalQueue) save(pending []pendingChange) error {
+	data, err := json.MarshalIndent(pending, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(q.path), filepath.Base(q.path)+".*")
+	if err != nil {
+		return fmt.Errorf("could not write approval queue: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("could not write approval queue: %w", err)
+	}
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("could not write approval queue: %w", err)
+	}
+	if err = os.Rename(tmp.Name(), q.path); err != nil {
+		return fmt.Errorf("could not write approval queue: %w", err)
+	}
+	return nil
+}
+
+// reject marks the pending change as rejected, it is kept until it expires after expiry
+func (q *approvalQueue) reject(id string, expiry time.Duration) (*pendingChange, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	pending, err := q.load()
+	if err != nil {
+		return nil, err
+	}
+	i, err := find(pending, id)
+	if err != nil {
+		return nil, err
+	}
+	pending[i].Rejected = true
+	pending[i].Expires = time.Now().UTC().Add(expiry)
+	if err = q.save(pending); err != nil {
+		return nil, err
+	}
+	return &pending[i], nil
+}
+
+func newPendingChangeID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// requiresApproval returns true if the changes of the zone must be approved
+func (p *Provider) requiresApproval(zone string) bool {
+	return slices.ContainsFunc(p.config.ApprovalZones, func(z string) bool { return strings.EqualFold(z, zone) })
+}
+
+// approvalChanges returns the changes of the plan requiring approval with the zone they belong to.
+// A PTR change derived from an A change requires approval with its A change, so the A change is returned
+// for both.
+func (p *Provider) approvalChanges(changesByZone map[string][]*infobloxChange) map[*infobloxChange]string {
+	queue := map[*infobloxChange]string{}
+	zoneOf := map[*infobloxChange]string{}
+	for zone, changes := range changesByZone {
+		for _, change := range changes {
+			if change.derivedFrom == nil {
+				zoneOf[change] = zone
+			}
+		}
+	}
+	for zone, changes := range changesByZone {
+		if !p.requiresApproval(zone) {
+			continue
+		}
+		for _, change := range changes {
+			source := change
+			if change.derivedFrom != nil {
+				source = change.derivedFrom
+			}
+			queue[source] = zoneOf[source]
+		}
+	}
+	return queue
+}
+
+// queueForApproval moves the changes of zones requiring approval from the plan to the approval queue.
+// Changes already waiting for approval or rejected are not queued again, as external-dns sends them on every sync.
+// PTR changes derived from A changes follow their A change: the A change is queued if either of them is in
+// a zone requiring approval, and its PTR is derived again when it is approved.
+func (p *Provider) queueForApproval(changesByZone map[string][]*infobloxChange) error {
+	if p.approvals == nil {
+		return nil
+	}
+	queue := p.approvalChanges(changesByZone)
+	if len(queue) == 0 {
+		return nil
+	}
+	for zone, changes := range changesByZone {
+		changesByZone[zone] = slices.DeleteFunc(changes, func(c *infobloxChange) bool {
+			_, queued := queue[c]
+			_, sourceQueued := queue[c.derivedFrom]
+			return queued || c.derivedFrom != nil && sourceQueued
+		})
+		if len(changesByZone[zone]) == 0 {
+			delete(changesByZone, zone)
+		}
+	}
+
+	p.approvals.mu.Lock()
+	defer p.approvals.mu.Unlock()
+	pending, err := p.approvals.load()
+	if err != nil {
+		return err
+	}
+	queued := 0
+	now := time.Now().UTC()
+	for change, zone := range queue {
+		hash := changeHash(zone, change.Action, change.Endpoint)
+		if slices.ContainsFunc(pending, func(c pendingChange) bool { return c.Hash == hash }) {
+			continue
+		}
+		record, ref, _, err := p.translateChange(zone, change)
+		if err != nil {
+			return err
+		}
+		target, ttl := endpointValues(record.obj)
+		pending = append(pending, pendingChange{
+			PendingChange: webhook.PendingChange{
+				ID:       newPendingChangeID(),
+				Zone:     zone,
+				Action:   change.Action,
+				Endpoint: change.Endpoint,
+				Operation: webhook.Operation{
+					Action:     change.Action,
+					Object:     record.obj.ObjectType(),
+					Name:       change.Endpoint.DNSName,
+					RecordType: change.Endpoint.RecordType,
+					Target:     target,
+					TTL:        ttl,
+					Zone:       zone,
+					Ref:        ref,
+				},
+				Created: now,
+				Expires: now.Add(p.config.ApprovalExpiry),
+			},
+			Hash:     hash,
+			Settings: change.settings,
+		})
+		queued++
+		log.WithFields(log.Fields{
+			"action": change.Action,
+			"record": change.Endpoint.DNSName,
+			"type":   change.Endpoint.RecordType,
+			"zone":   zone,
+		}).Info("Change requires approval, queued")
+	}
+	if queued == 0 {
+		return nil
+	}
+	return p.approvals.save(pending)
+}
+
+// PendingChanges lists the changes waiting for approval
+func (p *Provider) PendingChanges() ([]webhook.PendingChange, error) {
+	if p.approvals == nil {
+		return nil, ErrApprovalsDisabled
+	}
+	p.approvals.mu.Lock()
+	defer p.approvals.mu.Unlock()
+	pending, err := p.approvals.load()
+	if err != nil {
+		return nil, err
+	}
+	result := []webhook.PendingChange{}
+	for _, c := range pending {
+		if !c.Rejected {
+			result = append(result, c.PendingChange)
+		}
+	}
+	return result, nil
+}
+
+// Approve applies the change and removes it from the approval queue. The change is planned like
+// the changes of external-dns, so the policy and the deletion thresholds still apply to it and
+// INFOBLOX_CREATE_PTR derives its PTR. The change stays queued if it can't be applied.
+func (p *Provider) Approve(_ context.Context, id string) error {
+	if p.approvals == nil {
+		return ErrApprovalsDisabled
+	}
+	p.approvals.mu.Lock()
+	defer p.approvals.mu.Unlock()
+	pending, err := p.approvals.load()
+	if err != nil {
+		return err
+	}
+	i, err := find(pending, id)
+	if err != nil {
+		return err
+	}
+	change := pending[i]
+	log.Infof("applying approved %s of %s record '%s' in zone '%s'",
+		change.Action, change.Endpoint.RecordType, change.Endpoint.DNSName, change.Zone)
+	changesByZone, err := p.zoneChanges([]*infobloxChange{
+		{Action: change.Action, Endpoint: change.Endpoint, settings: change.Settings},
+	})
+	if err != nil {
+		return err
+	}
+	if err = p.executeChanges(changesByZone); err != nil {
+		return err
+	}
+	return p.approvals.save(slices.Delete(pending, i, i+1))
+}
+
+// Reject discards the pending change. The change is kept as rejected until INFOBLOX_APPROVAL_EXPIRY passed,
+// so it isn't queued again when external-dns sends it with its next sync.
+func (p *Provider) Reject(id string) error {
+	if p.approvals == nil {
+		return ErrApprovalsDisabled
+	}
+	pending, err := p.approvals.reject(id, p.config.ApprovalExpiry)
+	if err != nil {
+		return err
+	}
+	log.Infof("rejected %s of %s record '%s' in zone '%s'",
+		pending.Action, pending.Endpoint.RecordType, pending.Endpoint.DNSName, pending.Zone)
+	return nil
+}
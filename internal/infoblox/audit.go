@@ -0,0 +1,171 @@
+package infoblox
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	ibclient "github.com/infobloxopen/infoblox-go-client/v2"
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// auditStdout selects stdout as the INFOBLOX_AUDIT_LOG sink
+const auditStdout = "stdout"
+
+// AuditEntry is a single line of the audit log, written for every change sent to Infoblox
+type AuditEntry struct {
+	Time       time.Time `json:"time"`
+	Action     string    `json:"action"`
+	RecordType string    `json:"recordType"`
+	Name       string    `json:"name"`
+	OldTarget  string    `json:"oldTarget,omitempty"`
+	NewTarget  string    `json:"newTarget,omitempty"`
+	OldTTL     int64     `json:"oldTTL,omitempty"`
+	NewTTL     int64     `json:"newTTL,omitempty"`
+	Zone       string    `json:"zone"`
+	View       string    `json:"view"`
+	Ref        string    `json:"ref,omitempty"`
+	// Owner and Resource are the labels external-dns sets on the endpoint
+	Owner    string `json:"owner,omitempty"`
+	Resource string `json:"resource,omitempty"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// auditLog appends AuditEntry documents as JSON lines to its sink
+type auditLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// newAuditLog opens the audit log sink, which is stdout or a file opened for appending
+func newAuditLog(sink string) (*auditLog, error) {
+	if sink == auditStdout {
+		return &auditLog{w: os.Stdout}, nil
+	}
+	f, err := os.OpenFile(sink, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("could not open audit log: %w", err)
+	}
+	return &auditLog{w: f}, nil
+}
+
+// write appends the entry to the audit log. Failures are logged, they don't fail the change
+// which was already sent to Infoblox.
+func (a *auditLog) write(entry AuditEntry) {
+	if a == nil {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		log.WithError(err).Error("could not encode audit log entry")
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err = a.w.Write(append(data, '\n')); err != nil {
+		log.WithError(err).Error("could not write audit log entry")
+	}
+}
+
+// close closes the audit log file, stdout is kept open
+func (a *auditLog) close() error {
+	if a == nil {
+		return nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if f, ok := a.w.(*os.File); ok && f != os.Stdout {
+		return f.Close()
+	}
+	return nil
+}
+
+// newAuditEntry describes the change of the record. The old target and TTL are taken
+// from the record currently stored in Infoblox, the new ones from the requested record.
+func newAuditEntry(zone, view string, change *infobloxChange, record *infobloxRecordSet, ref string, err error) AuditEntry {
+	entry := AuditEntry{
+		Time:       time.Now().UTC(),
+		Action:     change.Action,
+		RecordType: change.Endpoint.RecordType,
+		Name:       change.Endpoint.DNSName,
+		Zone:       zone,
+		View:       view,
+		Ref:        ref,
+		Owner:      change.Endpoint.Labels[endpoint.OwnerLabelKey],
+		Resource:   change.Endpoint.Labels[endpoint.ResourceLabelKey],
+		Status:     string(ChangeStatusApplied),
+	}
+	newTarget, newTTL := recordValues(record.obj)
+	if change.Action != infobloxDelete {
+		entry.NewTarget, entry.NewTTL = newTarget, newTTL
+	}
+	if current := currentRecord(record); current != nil && change.Action != infobloxCreate {
+		entry.OldTarget, entry.OldTTL = recordValues(current)
+	}
+	if err != nil {
+		entry.Status = string(ChangeStatusFailed)
+		entry.Error = err.Error()
+	}
+	return entry
+}
+
+// currentRecord returns the record fetched from Infoblox or nil if it wasn't found
+func currentRecord(record *infobloxRecordSet) ibclient.IBObject {
+	switch res := record.res.(type) {
+	case *[]ibclient.RecordA:
+		if len(*res) > 0 {
+			return &(*res)[0]
+		}
+	case *[]ibclient.RecordTXT:
+		if len(*res) > 0 {
+			return &(*res)[0]
+		}
+	case *[]ibclient.RecordCNAME:
+		if len(*res) > 0 {
+			return &(*res)[0]
+		}
+	case *[]ibclient.RecordPTR:
+		if len(*res) > 0 {
+			return &(*res)[0]
+		}
+	}
+	return nil
+}
+
+// recordValues returns the target and TTL of the record
+func recordValues(obj ibclient.IBObject) (string, int64) {
+	switch r := obj.(type) {
+	case *ibclient.RecordA:
+		return AsString(r.Ipv4Addr), AsInt64(r.Ttl)
+	case *ibclient.RecordTXT:
+		return AsString(r.Text), AsInt64(r.Ttl)
+	case *ibclient.RecordCNAME:
+		return AsString(r.Canonical), AsInt64(r.Ttl)
+	case *ibclient.RecordPTR:
+		return AsString(r.PtrdName), AsInt64(r.Ttl)
+	}
+	return "", 0
+}
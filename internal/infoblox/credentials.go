@@ -0,0 +1,117 @@
+package infoblox
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	ibclient "github.com/infobloxopen/infoblox-go-client/v2"
+	log "github.com/sirupsen/logrus"
+)
+
+// readCredential returns the trimmed content of file if set, value otherwise
+func readCredential(value, file string) (string, error) {
+	if file == "" {
+		return value, nil
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// reloadingConnector delegates to a connector which is rebuilt when the credential files change.
+// Requests in flight complete with the connector they started with.
+type reloadingConnector struct {
+	cfg      *StartupConfig
+	files    []string
+	mu       sync.RWMutex
+	modTimes []time.Time
+	current  ibclient.IBConnector
+}
+
+func newReloadingConnector(cfg *StartupConfig, client ibclient.IBConnector) *reloadingConnector {
+	c := &reloadingConnector{cfg: cfg, current: client}
+	for _, file := range []string{cfg.UsernameFile, cfg.PasswordFile} {
+		if file != "" {
+			c.files = append(c.files, file)
+		}
+	}
+	c.modTimes = c.stat()
+	return c
+}
+
+// stat returns the modification times of the credential files, the zero time for missing files
+func (c *reloadingConnector) stat() []time.Time {
+	modTimes := make([]time.Time, len(c.files))
+	for i, file := range c.files {
+		if info, err := os.Stat(file); err == nil {
+			modTimes[i] = info.ModTime()
+		}
+	}
+	return modTimes
+}
+
+// connector returns the connector for the current credentials. When they can't be loaded,
+// the previous connector is kept until the files change again.
+func (c *reloadingConnector) connector() ibclient.IBConnector {
+	modTimes := c.stat()
+	c.mu.RLock()
+	current, changed := c.current, !slices.EqualFunc(modTimes, c.modTimes, time.Time.Equal)
+	c.mu.RUnlock()
+	if !changed {
+		return current
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if slices.EqualFunc(modTimes, c.modTimes, time.Time.Equal) {
+		return c.current
+	}
+	c.modTimes = modTimes
+	client, err := newConnector(c.cfg)
+	if err != nil {
+		log.WithError(err).Error("could not reload WAPI credentials, keeping the previous ones")
+		return c.current
+	}
+	log.Info("reloaded WAPI credentials")
+	c.current = client
+	return client
+}
+
+func (c *reloadingConnector) CreateObject(obj ibclient.IBObject) (string, error) {
+	return c.connector().CreateObject(obj)
+}
+
+func (c *reloadingConnector) GetObject(obj ibclient.IBObject, ref string, queryParams *ibclient.QueryParams, res interface{}) error {
+	return c.connector().GetObject(obj, ref, queryParams, res)
+}
+
+func (c *reloadingConnector) DeleteObject(ref string) (string, error) {
+	return c.connector().DeleteObject(ref)
+}
+
+func (c *reloadingConnector) UpdateObject(obj ibclient.IBObject, ref string) (string, error) {
+	return c.connector().UpdateObject(obj, ref)
+}
@@ -0,0 +1,292 @@
+package infoblox
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/pkg/webhook"
+)
+
+// ErrorKind classifies provider errors, so callers can report them appropriately
+type ErrorKind string
+
+const (
+	// ErrorKindUpstream is a failing or unreachable WAPI request
+	ErrorKindUpstream ErrorKind = "UpstreamError"
+	// ErrorKindConflict is a change rejected by WAPI because it conflicts with existing data
+	ErrorKindConflict ErrorKind = "Conflict"
+	// ErrorKindUnsupported is a record type the provider can't handle
+	ErrorKindUnsupported ErrorKind = "UnsupportedRecordType"
+	// ErrorKindRejected is a plan refused by the safety rules of the provider
+	ErrorKindRejected ErrorKind = "PlanRejected"
+	// ErrorKindInvalid is a change rejected by WAPI as invalid, like a malformed name or target
+	ErrorKindInvalid ErrorKind = "InvalidChange"
+
+	// WAPI error code reported when an object conflicts with an existing one
+	wapiConflictCode = "Client.Ibap.Data.Conflict"
+	// prefix of the errors of failed WAPI requests, followed by the HTTP status code
+	wapiRequestError = "WAPI request error: "
+)
+
+// kindStatus is the HTTP status reported for each kind of error
+var kindStatus = map[ErrorKind]int{
+	ErrorKindUpstream:    http.StatusBadGateway,
+	ErrorKindConflict:    http.StatusConflict,
+	ErrorKindUnsupported: http.StatusUnprocessableEntity,
+	ErrorKindRejected:    http.StatusForbidden,
+	ErrorKindInvalid:     http.StatusUnprocessableEntity,
+}
+
+// statusError is a provider error with a fixed error code and HTTP status
+type statusError struct {
+	code   string
+	status int
+	msg    string
+}
+
+func (e *statusError) Error() string {
+	return e.msg
+}
+
+// Code returns the error code reported to the webhook clients
+func (e *statusError) Code() string {
+	return e.code
+}
+
+// HTTPStatus returns the status code of the webhook response
+func (e *statusError) HTTPStatus() int {
+	return e.status
+}
+
+// RecordError describes a failure while reading or changing records
+type RecordError struct {
+	Kind       ErrorKind
+	Action     string
+	Name       string
+	RecordType string
+	Zone       string
+	Err        error
+}
+
+func (e *RecordError) Error() string {
+	var msg string
+	switch {
+	case e.Name != "":
+		msg = fmt.Sprintf("could not %s %s record '%s'", strings.ToLower(e.Action), e.RecordType, e.Name)
+	case e.RecordType != "":
+		msg = fmt.Sprintf("could not fetch %s records", e.RecordType)
+	default:
+		return e.Err.Error()
+	}
+	if e.Zone != "" {
+		msg += fmt.Sprintf(" in zone '%s'", e.Zone)
+	}
+	return fmt.Sprintf("%s: %v", msg, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+// Code returns the error kind, reported to the webhook clients as error code
+func (e *RecordError) Code() string {
+	return string(e.Kind)
+}
+
+// HTTPStatus returns the status code of the webhook response for the error kind
+func (e *RecordError) HTTPStatus() int {
+	if status, ok := kindStatus[e.Kind]; ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// Record returns the record the error is about
+func (e *RecordError) Record() (name, recordType, zone string) {
+	return e.Name, e.RecordType, e.Zone
+}
+
+// WAPIText returns the error text reported by WAPI, if the error came from WAPI
+func (e *RecordError) WAPIText() string {
+	if wapiErr := parseWAPIError(e.Err); wapiErr != nil {
+		return wapiErr.Text
+	}
+	return ""
+}
+
+// ChangeStatus is the outcome of a single change
+type ChangeStatus string
+
+const (
+	ChangeStatusApplied ChangeStatus = "applied"
+	ChangeStatusSkipped ChangeStatus = "skipped"
+	ChangeStatusFailed  ChangeStatus = "failed"
+)
+
+// ChangeResult reports the outcome of a single change submitted to Infoblox
+type ChangeResult struct {
+	Action     string
+	Name       string
+	RecordType string
+	Target     string
+	Zone       string
+	Status     ChangeStatus
+	Err        error
+}
+
+func newChangeResult(zone string, change *infobloxChange, status ChangeStatus, err error) ChangeResult {
+	result := ChangeResult{
+		Action:     change.Action,
+		Name:       change.Endpoint.DNSName,
+		RecordType: change.Endpoint.RecordType,
+		Zone:       zone,
+		Status:     status,
+		Err:        err,
+	}
+	if len(change.Endpoint.Targets) > 0 {
+		result.Target = change.Endpoint.Targets[0]
+	}
+	return result
+}
+
+// sortChangeResults sorts the results by zone, name and record type. Results of the same record keep
+// the order the changes were submitted in.
+func sortChangeResults(results []ChangeResult) {
+	sort.SliceStable(results, func(i, j int) bool {
+		a, b := results[i], results[j]
+		if a.Zone != b.Zone {
+			return a.Zone < b.Zone
+		}
+		if a.Name != b.Name {
+			return a.Name < b.Name
+		}
+		return a.RecordType < b.RecordType
+	})
+}
+
+// ApplyError is returned when changes failed in continue-on-error mode. It joins the errors
+// of all failed changes and keeps the results of every attempted change.
+type ApplyError struct {
+	Results []ChangeResult
+	err     error
+}
+
+// NewApplyError joins the errors of the failed results
+func NewApplyError(results []ChangeResult) *ApplyError {
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, result.Err)
+		}
+	}
+	return &ApplyError{Results: results, err: errors.Join(errs...)}
+}
+
+func (e *ApplyError) Error() string {
+	return e.err.Error()
+}
+
+func (e *ApplyError) Unwrap() error {
+	return e.err
+}
+
+// ChangeOutcomes returns the results of every attempted change, reported to the webhook clients
+func (e *ApplyError) ChangeOutcomes() []webhook.ChangeOutcome {
+	outcomes := make([]webhook.ChangeOutcome, 0, len(e.Results))
+	for _, result := range e.Results {
+		outcome := webhook.ChangeOutcome{
+			Action:     result.Action,
+			Record:     result.Name,
+			RecordType: result.RecordType,
+			Target:     result.Target,
+			Zone:       result.Zone,
+			Status:     string(result.Status),
+		}
+		if result.Err != nil {
+			outcome.Error = result.Err.Error()
+		}
+		outcomes = append(outcomes, outcome)
+	}
+	return outcomes
+}
+
+// wapiError is the error document returned by WAPI
+type wapiError struct {
+	Error string `json:"Error"`
+	Code  string `json:"code"`
+	Text  string `json:"text"`
+}
+
+// parseWAPIError extracts the WAPI error document from errors of the infoblox client,
+// which embeds the response body after a "Contents:" line
+func parseWAPIError(err error) *wapiError {
+	if err == nil {
+		return nil
+	}
+	_, contents, found := strings.Cut(err.Error(), "Contents:\n")
+	if !found {
+		return nil
+	}
+	wapiErr := &wapiError{}
+	if json.Unmarshal([]byte(strings.TrimSpace(contents)), wapiErr) != nil {
+		return nil
+	}
+	return wapiErr
+}
+
+// wapiStatus returns the HTTP status code of the failed WAPI request, 0 if the request got no response
+func wapiStatus(err error) int {
+	_, msg, found := strings.Cut(err.Error(), wapiRequestError)
+	if !found {
+		return 0
+	}
+	var status int
+	if _, scanErr := fmt.Sscanf(msg, "%d(", &status); scanErr != nil {
+		return 0
+	}
+	return status
+}
+
+// newUpstreamError wraps the error of a WAPI request, classifying conflicts and changes WAPI
+// rejected as invalid. Failed authentication of the provider and server errors stay upstream errors.
+func newUpstreamError(action, name, recordType, zone string, err error) *RecordError {
+	kind := ErrorKindUpstream
+	status := wapiStatus(err)
+	if status >= http.StatusBadRequest && status < http.StatusInternalServerError &&
+		status != http.StatusUnauthorized && status != http.StatusForbidden {
+		kind = ErrorKindInvalid
+		if wapiErr := parseWAPIError(err); wapiErr != nil && wapiErr.Code == wapiConflictCode {
+			kind = ErrorKindConflict
+		}
+	}
+	return &RecordError{
+		Kind:       kind,
+		Action:     action,
+		Name:       name,
+		RecordType: recordType,
+		Zone:       zone,
+		Err:        err,
+	}
+}
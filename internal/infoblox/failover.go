@@ -0,0 +1,206 @@
+package infoblox
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	ibclient "github.com/infobloxopen/infoblox-go-client/v2"
+	log "github.com/sirupsen/logrus"
+)
+
+// newHostConnector creates the connector of a single grid host, given as host or host:port
+func newHostConnector(cfg *StartupConfig, host string) (ibclient.IBConnector, error) {
+	hostCfg := *cfg
+	hostCfg.Host = host
+	if h, port, err := net.SplitHostPort(host); err == nil {
+		hostCfg.Host = h
+		if hostCfg.Port, err = strconv.Atoi(port); err != nil {
+			return nil, fmt.Errorf("invalid port of grid host '%s'", host)
+		}
+	}
+	client, err := newConnector(&hostCfg)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.UsernameFile != "" || cfg.PasswordFile != "" {
+		return newReloadingConnector(&hostCfg, client), nil
+	}
+	return client, nil
+}
+
+// newGridConnector creates the connector of the grid hosts, failing over in the given order
+func newGridConnector(cfg *StartupConfig, hosts []string) (ibclient.IBConnector, error) {
+	clients := make([]ibclient.IBConnector, 0, len(hosts))
+	for _, host := range hosts {
+		client, err := newHostConnector(cfg, host)
+		if err != nil {
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+	if len(clients) == 1 {
+		return clients[0], nil
+	}
+	c := newFailoverConnector(hosts, clients)
+	if cfg.HealthCheckInterval > 0 {
+		go c.run(cfg.HealthCheckInterval)
+	}
+	return c, nil
+}
+
+// failoverConnector sends the requests to the first healthy grid host. Hosts which can't be reached
+// are marked unhealthy and the request is retried on the next host. Unhealthy hosts are probed by
+// the health check and used again once they respond.
+type failoverConnector struct {
+	hosts   []string
+	clients []ibclient.IBConnector
+	mu      sync.Mutex
+	healthy []bool
+	done    chan struct{}
+	once    sync.Once
+}
+
+func newFailoverConnector(hosts []string, clients []ibclient.IBConnector) *failoverConnector {
+	healthy := make([]bool, len(hosts))
+	for i := range healthy {
+		healthy[i] = true
+	}
+	return &failoverConnector{hosts: hosts, clients: clients, healthy: healthy, done: make(chan struct{})}
+}
+
+// isUnreachable returns true for errors of requests which didn't get a response from the host
+func isUnreachable(err error) bool {
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// isNotConnected returns true for errors of requests which failed to connect to the host, so they weren't sent.
+// Writes may have been applied by the host when the response was lost, so only these are safe to send again.
+func isNotConnected(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// candidates returns the hosts in the order they are tried, healthy hosts first in the configured order
+func (c *failoverConnector) candidates() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var healthy, unhealthy []int
+	for i := range c.hosts {
+		if c.healthy[i] {
+			healthy = append(healthy, i)
+		} else {
+			unhealthy = append(unhealthy, i)
+		}
+	}
+	return append(healthy, unhealthy...)
+}
+
+func (c *failoverConnector) setHealthy(i int, healthy bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if healthy && !c.healthy[i] {
+		log.Infof("grid host '%s' is reachable again", c.hosts[i])
+	}
+	c.healthy[i] = healthy
+}
+
+// do runs the request on the grid hosts until one of them responds. Writes are not idempotent, they are
+// only sent to the next host when the connection to the host failed.
+func (c *failoverConnector) do(idempotent bool, request func(ibclient.IBConnector) error) (err error) {
+	for _, i := range c.candidates() {
+		if err = request(c.clients[i]); !isUnreachable(err) {
+			c.setHealthy(i, true)
+			return err
+		}
+		log.WithError(err).Warnf("grid host '%s' is unreachable", c.hosts[i])
+		c.setHealthy(i, false)
+		if !idempotent && !isNotConnected(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// checkHealth probes the grid hosts by the WAPI schema request
+func (c *failoverConnector) checkHealth() {
+	for i, client := range c.clients {
+		var schema wapiSchema
+		err := client.GetObject(&schema, "", ibclient.NewQueryParams(false, map[string]string{"_schema": "1"}), &schema)
+		if isUnreachable(err) {
+			log.WithError(err).Debugf("health check of grid host '%s' failed", c.hosts[i])
+		}
+		c.setHealthy(i, !isUnreachable(err))
+	}
+}
+
+func (c *failoverConnector) run(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.checkHealth()
+		case <-c.done:
+			return
+		}
+	}
+}
+
+// stop ends the health checks
+func (c *failoverConnector) stop() {
+	c.once.Do(func() { close(c.done) })
+}
+
+func (c *failoverConnector) CreateObject(obj ibclient.IBObject) (ref string, err error) {
+	err = c.do(false, func(client ibclient.IBConnector) (err error) {
+		ref, err = client.CreateObject(obj)
+		return err
+	})
+	return ref, err
+}
+
+func (c *failoverConnector) GetObject(obj ibclient.IBObject, ref string, queryParams *ibclient.QueryParams, res interface{}) error {
+	return c.do(true, func(client ibclient.IBConnector) error {
+		return client.GetObject(obj, ref, queryParams, res)
+	})
+}
+
+func (c *failoverConnector) DeleteObject(ref string) (refRes string, err error) {
+	err = c.do(false, func(client ibclient.IBConnector) (err error) {
+		refRes, err = client.DeleteObject(ref)
+		return err
+	})
+	return refRes, err
+}
+
+func (c *failoverConnector) UpdateObject(obj ibclient.IBObject, ref string) (refRes string, err error) {
+	err = c.do(false, func(client ibclient.IBConnector) (err error) {
+		refRes, err = client.UpdateObject(obj, ref)
+		return err
+	})
+	return refRes, err
+}
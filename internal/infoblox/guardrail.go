@@ -0,0 +1,111 @@
+package infoblox
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+// checkDeletionThresholds refuses plans which delete more records of a zone than
+// INFOBLOX_MAX_DELETES or more than INFOBLOX_MAX_DELETE_PERCENT of the records currently in the zone.
+// With INFOBLOX_OWNER_EA set, the percentage is taken of the records owned by external-dns only.
+func (p *Provider) checkDeletionThresholds(changesByZone map[string][]*infobloxChange) error {
+	if p.config.MaxDeletes <= 0 && p.config.MaxDeletePercent <= 0 {
+		return nil
+	}
+
+	for zone, changes := range changesByZone {
+		deletes := 0
+		for _, change := range changes {
+			if change.Action == infobloxDelete {
+				deletes++
+			}
+		}
+		if deletes == 0 {
+			continue
+		}
+
+		var reason string
+		if p.config.MaxDeletes > 0 && deletes > p.config.MaxDeletes {
+			reason = fmt.Sprintf("exceeds the limit of %d deletions per zone", p.config.MaxDeletes)
+		} else if p.config.MaxDeletePercent > 0 {
+			managed, err := p.countZoneRecords(zone)
+			if err != nil {
+				return err
+			}
+			if managed > 0 && deletes*100 > managed*p.config.MaxDeletePercent {
+				reason = fmt.Sprintf("exceeds the limit of %d%% of the %d %s in the zone", p.config.MaxDeletePercent, managed, p.countedRecords())
+			}
+		}
+		if reason == "" {
+			continue
+		}
+
+		if p.config.AllowMassDelete {
+			log.Warnf("deleting %d records in zone '%s' %s, allowed by INFOBLOX_ALLOW_MASS_DELETE", deletes, zone, reason)
+			continue
+		}
+		err := &RecordError{
+			Kind: ErrorKindRejected,
+			Zone: zone,
+			Err: fmt.Errorf("refusing to delete %d records in zone '%s': %s, set INFOBLOX_ALLOW_MASS_DELETE=true to apply the plan anyway",
+				deletes, zone, reason),
+		}
+		log.Error(err)
+		return err
+	}
+	return nil
+}
+
+// countedRecords describes the records counted by countZoneRecords
+func (p *Provider) countedRecords() string {
+	if p.config.OwnerEA != "" {
+		return "owned records"
+	}
+	return "records"
+}
+
+// countZoneRecords returns the number of record targets currently in the zone. With INFOBLOX_OWNER_EA set,
+// only the records owned by external-dns are counted: the records with an owner and the TXT registry records.
+// Without it, the owned records can't be told apart from the unmanaged ones, and every record is counted.
+func (p *Provider) countZoneRecords(zone string) (int, error) {
+	endpoints, err := p.zoneRecords(zone, 0)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, ep := range endpoints {
+		if p.config.OwnerEA == "" || ep.Labels[endpoint.OwnerLabelKey] != "" {
+			count += len(ep.Targets)
+			continue
+		}
+		if ep.RecordType != endpoint.RecordTypeTXT {
+			continue
+		}
+		for _, target := range ep.Targets {
+			if _, err := endpoint.NewLabelsFromStringPlain(target); err == nil {
+				count++
+			}
+		}
+	}
+	return count, nil
+}
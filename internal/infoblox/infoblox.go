@@ -20,13 +20,17 @@ Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
 
 import (
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"os"
 	"reflect"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	ibclient "github.com/infobloxopen/infoblox-go-client/v2"
 	log "github.com/sirupsen/logrus"
@@ -52,16 +56,21 @@ func isNotFoundError(err error) bool {
 type Provider struct {
 	provider.BaseProvider
 	client       ibclient.IBConnector
+	reader       ibclient.IBConnector
 	domainFilter endpoint.DomainFilter
 	config       *StartupConfig
+	policy       *Policy
+	audit        *auditLog
+	approvals    *approvalQueue
+	notifier     *notifier
 }
 
 // StartupConfig clarifies the method signature
 type StartupConfig struct {
 	Host       string `env:"INFOBLOX_HOST,required" envDefault:"localhost"`
 	Port       int    `env:"INFOBLOX_PORT,required" envDefault:"443"`
-	Username   string `env:"INFOBLOX_WAPI_USER,required"`
-	Password   string `env:"INFOBLOX_WAPI_PASSWORD,required"`
+	Username   string `env:"INFOBLOX_WAPI_USER"`
+	Password   string `env:"INFOBLOX_WAPI_PASSWORD"`
 	Version    string `env:"INFOBLOX_VERSION,required"`
 	SSLVerify  bool   `env:"INFOBLOX_SSL_VERIFY" envDefault:"true"`
 	DryRun     bool   `env:"INFOBLOX_DRY_RUN" envDefault:"false"`
@@ -69,8 +78,61 @@ type StartupConfig struct {
 	MaxResults int    `env:"INFOBLOX_MAX_RESULTS" envDefault:"1500"`
 	CreatePTR  bool   `env:"INFOBLOX_CREATE_PTR" envDefault:"false"`
 	DefaultTTL int    `env:"INFOBLOX_DEFAULT_TTL" envDefault:"300"`
-	FQDNRegEx  string
-	NameRegEx  string
+	// FailoverHosts are tried in order when Host is unreachable, ReadHost is a read-only grid member preferred by Records
+	FailoverHosts       []string      `env:"INFOBLOX_FAILOVER_HOSTS" envSeparator:","`
+	ReadHost            string        `env:"INFOBLOX_READ_HOST"`
+	HealthCheckInterval time.Duration `env:"INFOBLOX_HEALTH_CHECK_INTERVAL" envDefault:"30s"`
+	// UsernameFile and PasswordFile replace Username and Password, the connector is rebuilt when they change
+	UsernameFile string `env:"INFOBLOX_WAPI_USER_FILE"`
+	PasswordFile string `env:"INFOBLOX_WAPI_PASSWORD_FILE"`
+	// ClientCertFile and ClientKeyFile authenticate to WAPI by a client certificate instead of Username and Password
+	ClientCertFile string `env:"INFOBLOX_CLIENT_CERT_FILE"`
+	ClientKeyFile  string `env:"INFOBLOX_CLIENT_KEY_FILE"`
+	// CAFile is the CA bundle verifying the grid master instead of the system roots
+	CAFile string `env:"INFOBLOX_CA_FILE"`
+	// ServerFingerprints pin the SHA-256 fingerprints of the certificates accepted from the grid master
+	ServerFingerprints []string `env:"INFOBLOX_SERVER_FINGERPRINTS" envSeparator:","`
+	// ProxyURL is the proxy to the grid master, bypassed for the hosts in NoProxy; HTTPS_PROXY applies if unset
+	ProxyURL string   `env:"INFOBLOX_PROXY_URL"`
+	NoProxy  []string `env:"INFOBLOX_NO_PROXY" envSeparator:","`
+	// StartupCheck runs SelfCheck when the provider is initialized
+	StartupCheck bool `env:"INFOBLOX_STARTUP_CHECK" envDefault:"true"`
+	// ContinueOnError attempts all changes of a plan instead of aborting on the first failure
+	ContinueOnError bool `env:"INFOBLOX_CONTINUE_ON_ERROR" envDefault:"false"`
+	// MaxDeletes and MaxDeletePercent limit the deletions per zone in a single plan, 0 disables the limit
+	MaxDeletes       int  `env:"INFOBLOX_MAX_DELETES" envDefault:"0"`
+	MaxDeletePercent int  `env:"INFOBLOX_MAX_DELETE_PERCENT" envDefault:"0"`
+	AllowMassDelete  bool `env:"INFOBLOX_ALLOW_MASS_DELETE" envDefault:"false"`
+	// PolicyFile is the path to the Policy protecting records from changes
+	PolicyFile string `env:"INFOBLOX_POLICY_FILE"`
+	// Policy is the Policy itself as YAML or JSON, a structured value in the config file
+	Policy string `env:"INFOBLOX_POLICY"`
+	// AuditLog is the sink of the audit log, stdout or a file path; empty disables the audit log
+	AuditLog string `env:"INFOBLOX_AUDIT_LOG"`
+	// SnapshotDir stores the state of records before they are changed, empty disables snapshots. The oldest snapshots
+	// are deleted beyond SnapshotMaxCount snapshots and after SnapshotMaxAge, 0 keeps them.
+	SnapshotDir      string        `env:"INFOBLOX_SNAPSHOT_DIR"`
+	SnapshotMaxCount int           `env:"INFOBLOX_SNAPSHOT_MAX_COUNT" envDefault:"100"`
+	SnapshotMaxAge   time.Duration `env:"INFOBLOX_SNAPSHOT_MAX_AGE" envDefault:"0"`
+	// ApprovalZones are the zones whose changes wait in the ApprovalQueue file until they are approved
+	ApprovalZones  []string      `env:"INFOBLOX_APPROVAL_ZONES" envSeparator:","`
+	ApprovalQueue  string        `env:"INFOBLOX_APPROVAL_QUEUE"`
+	ApprovalExpiry time.Duration `env:"INFOBLOX_APPROVAL_EXPIRY" envDefault:"24h"`
+	// NotifyURLs receive a notification of the changes applied by each plan, filtered by zone, record type and action
+	NotifyURLs        []string      `env:"INFOBLOX_NOTIFY_URLS" envSeparator:","`
+	NotifyTemplate    string        `env:"INFOBLOX_NOTIFY_TEMPLATE"`
+	NotifyZones       []string      `env:"INFOBLOX_NOTIFY_ZONES" envSeparator:","`
+	NotifyRecordTypes []string      `env:"INFOBLOX_NOTIFY_RECORD_TYPES" envSeparator:","`
+	NotifyActions     []string      `env:"INFOBLOX_NOTIFY_ACTIONS" envSeparator:","`
+	NotifyRetries     int           `env:"INFOBLOX_NOTIFY_RETRIES" envDefault:"3"`
+	NotifyTimeout     time.Duration `env:"INFOBLOX_NOTIFY_TIMEOUT" envDefault:"10s"`
+	// OwnerEA is the extensible attribute holding the owner ID of records, returned as their owner label
+	OwnerEA string `env:"INFOBLOX_OWNER_EA"`
+	// InheritTTL creates the records of endpoints without a TTL with use_ttl=false, inheriting the TTL of the zone
+	InheritTTL bool `env:"INFOBLOX_INHERIT_TTL" envDefault:"false"`
+
+	FQDNRegEx string
+	NameRegEx string
 }
 
 type infobloxRecordSet struct {
@@ -101,6 +163,10 @@ func NewExtendedRequestBuilder(maxResults int, fqdnRegEx string, nameRegEx strin
 // WapiRequestBuilder and then add the _max_requests parameter
 func (mrb *ExtendedRequestBuilder) BuildRequest(t ibclient.RequestType, obj ibclient.IBObject, ref string, queryParams *ibclient.QueryParams) (req *http.Request, err error) {
 	req, err = mrb.WapiRequestBuilder.BuildRequest(t, obj, ref, queryParams)
+	// the schema request does not accept any filtering parameters
+	if _, schemaQuery := obj.(*wapiSchema); schemaQuery {
+		return
+	}
 	if req.Method == "GET" {
 		query := req.URL.Query()
 		if mrb.maxResults > 0 {
@@ -121,17 +187,50 @@ func (mrb *ExtendedRequestBuilder) BuildRequest(t ibclient.RequestType, obj ibcl
 	return
 }
 
-// NewInfobloxProvider creates a new Infoblox provider.
-func NewInfobloxProvider(cfg *StartupConfig, domainFilter endpoint.DomainFilter) (*Provider, error) {
+// newAuthConfig authenticates by the client certificate if configured, by username and password otherwise.
+// The credential files take precedence over Username and Password. The key pair is validated here,
+// as the infoblox client exits the process on an invalid one.
+func newAuthConfig(cfg *StartupConfig) (authCfg ibclient.AuthConfig, err error) {
+	authCfg.Username, err = readCredential(cfg.Username, cfg.UsernameFile)
+	if err != nil {
+		return authCfg, fmt.Errorf("could not read WAPI user: %w", err)
+	}
+	authCfg.Password, err = readCredential(cfg.Password, cfg.PasswordFile)
+	if err != nil {
+		return authCfg, fmt.Errorf("could not read WAPI password: %w", err)
+	}
+	if cfg.ClientCertFile == "" && cfg.ClientKeyFile == "" {
+		if authCfg.Username == "" || authCfg.Password == "" {
+			return authCfg, fmt.Errorf("INFOBLOX_WAPI_USER and INFOBLOX_WAPI_PASSWORD are required unless INFOBLOX_CLIENT_CERT_FILE and INFOBLOX_CLIENT_KEY_FILE are set")
+		}
+		return authCfg, nil
+	}
+	if cfg.ClientCertFile == "" || cfg.ClientKeyFile == "" {
+		return authCfg, fmt.Errorf("INFOBLOX_CLIENT_CERT_FILE and INFOBLOX_CLIENT_KEY_FILE must be set together")
+	}
+	if authCfg.ClientCert, err = os.ReadFile(cfg.ClientCertFile); err != nil {
+		return authCfg, fmt.Errorf("could not read client certificate: %w", err)
+	}
+	if authCfg.ClientKey, err = os.ReadFile(cfg.ClientKeyFile); err != nil {
+		return authCfg, fmt.Errorf("could not read client key: %w", err)
+	}
+	if _, err = tls.X509KeyPair(authCfg.ClientCert, authCfg.ClientKey); err != nil {
+		return authCfg, fmt.Errorf("invalid client certificate '%s' or key '%s': %w", cfg.ClientCertFile, cfg.ClientKeyFile, err)
+	}
+	return authCfg, nil
+}
+
+// newConnector creates the WAPI connector with the current credentials
+func newConnector(cfg *StartupConfig) (ibclient.IBConnector, error) {
 	hostCfg := ibclient.HostConfig{
 		Host:    cfg.Host,
 		Port:    strconv.Itoa(cfg.Port),
 		Version: cfg.Version,
 	}
 
-	authCfg := ibclient.AuthConfig{
-		Username: cfg.Username,
-		Password: cfg.Password,
+	authCfg, err := newAuthConfig(cfg)
+	if err != nil {
+		return nil, err
 	}
 
 	httpPoolConnections := lookupEnvAtoi("EXTERNAL_DNS_INFOBLOX_HTTP_POOL_CONNECTIONS", 10)
@@ -143,10 +242,7 @@ func NewInfobloxProvider(cfg *StartupConfig, domainFilter endpoint.DomainFilter)
 		httpPoolConnections,
 	)
 
-	var (
-		requestBuilder ibclient.HttpRequestBuilder
-		err            error
-	)
+	var requestBuilder ibclient.HttpRequestBuilder
 	if cfg.MaxResults != 0 || cfg.FQDNRegEx != "" || cfg.NameRegEx != "" {
 		// use our own HttpRequestBuilder which sets _max_results parameter on GET requests
 		requestBuilder = NewExtendedRequestBuilder(cfg.MaxResults, cfg.FQDNRegEx, cfg.NameRegEx)
@@ -158,9 +254,18 @@ func NewInfobloxProvider(cfg *StartupConfig, domainFilter endpoint.DomainFilter)
 		}
 	}
 
-	requestor := &ibclient.WapiHttpRequestor{}
+	requestor, err := newHTTPRequestor(cfg)
+	if err != nil {
+		return nil, err
+	}
 
-	client, err := ibclient.NewConnector(hostCfg, authCfg, transportConfig, requestBuilder, requestor)
+	return ibclient.NewConnector(hostCfg, authCfg, transportConfig, requestBuilder, requestor)
+}
+
+// NewInfobloxProvider creates a new Infoblox provider.
+func NewInfobloxProvider(cfg *StartupConfig, domainFilter endpoint.DomainFilter) (*Provider, error) {
+	hosts := append([]string{cfg.Host}, cfg.FailoverHosts...)
+	client, err := newGridConnector(cfg, hosts)
 	if err != nil {
 		return nil, err
 	}
@@ -171,6 +276,53 @@ func NewInfobloxProvider(cfg *StartupConfig, domainFilter endpoint.DomainFilter)
 		config:       cfg,
 	}
 
+	if cfg.ReadHost != "" {
+		// Records falls back to the grid master when the read-only member is unreachable
+		provider.reader, err = newGridConnector(cfg, append([]string{cfg.ReadHost}, hosts...))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case cfg.PolicyFile != "" && cfg.Policy != "":
+		return nil, errors.New("INFOBLOX_POLICY_FILE and INFOBLOX_POLICY are mutually exclusive")
+	case cfg.PolicyFile != "":
+		provider.policy, err = LoadPolicy(cfg.PolicyFile)
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("loaded policy with %d protected rules from '%s'", len(provider.policy.Protected), cfg.PolicyFile)
+	case cfg.Policy != "":
+		provider.policy, err = ParsePolicy([]byte(cfg.Policy))
+		if err != nil {
+			return nil, fmt.Errorf("invalid INFOBLOX_POLICY: %w", err)
+		}
+		log.Infof("loaded policy with %d protected rules from INFOBLOX_POLICY", len(provider.policy.Protected))
+	}
+
+	if cfg.AuditLog != "" {
+		provider.audit, err = newAuditLog(cfg.AuditLog)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(cfg.ApprovalZones) > 0 {
+		if cfg.ApprovalQueue == "" {
+			return nil, fmt.Errorf("INFOBLOX_APPROVAL_QUEUE must be set when INFOBLOX_APPROVAL_ZONES is set")
+		}
+		provider.approvals = approvalQueueFor(cfg.ApprovalQueue)
+		log.Infof("changes of zones %v require approval", cfg.ApprovalZones)
+	}
+
+	if len(cfg.NotifyURLs) > 0 {
+		provider.notifier, err = newNotifier(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return provider, nil
 }
 
@@ -186,6 +338,18 @@ func recordQueryParams(zone string, view string) *ibclient.QueryParams {
 	return ibclient.NewQueryParams(false, searchFields)
 }
 
+// Close releases the resources of the provider once it is replaced: the health checks of the grid hosts
+// are stopped, pending notifications are sent and the audit log is closed
+func (p *Provider) Close() error {
+	for _, client := range []ibclient.IBConnector{p.client, p.reader} {
+		if c, ok := client.(*failoverConnector); ok {
+			c.stop()
+		}
+	}
+	p.notifier.wait()
+	return p.audit.close()
+}
+
 // Records gets the current records.
 func (p *Provider) Records(_ context.Context) (endpoints []*endpoint.Endpoint, err error) {
 	zones, err := p.zones()
@@ -194,66 +358,134 @@ func (p *Provider) Records(_ context.Context) (endpoints []*endpoint.Endpoint, e
 	}
 
 	for _, zone := range zones {
-		log.Debugf("fetch records from zone '%s'", zone.Fqdn)
-		searchParams := map[string]string{"zone": zone.Fqdn, "view": p.config.View}
-		var resA []ibclient.RecordA
-		objA := ibclient.NewEmptyRecordA()
-		objA.View = p.config.View
-		objA.Zone = zone.Fqdn
-		err = PagingGetObject(p.client, objA, "", searchParams, &resA)
-		if err != nil && !isNotFoundError(err) {
-			return nil, fmt.Errorf("could not fetch A records from zone '%s': %w", zone.Fqdn, err)
-		}
-		endpointsA := ToAResponseMap(resA).ToEndpoints()
-		endpoints = append(endpoints, endpointsA...)
-
-		// Include Host records since they should be treated synonymously with A records
-		var resH []ibclient.HostRecord
-		objH := ibclient.NewEmptyHostRecord()
-		objH.View = &p.config.View
-		objH.Zone = zone.Fqdn
-		err = PagingGetObject(p.client, objH, "", searchParams, &resH)
-		if err != nil && !isNotFoundError(err) {
-			return nil, fmt.Errorf("could not fetch host records from zone '%s': %w", zone.Fqdn, err)
-		}
-		endpointsHost := ToHostResponseMap(resH).ToEndpoints()
-		endpoints = append(endpoints, endpointsHost...)
-
-		var resC []ibclient.RecordCNAME
-		objC := ibclient.NewEmptyRecordCNAME()
-		objC.View = &p.config.View
-		objC.Zone = zone.Fqdn
-		err = PagingGetObject(p.client, objC, "", searchParams, &resC)
-		if err != nil && !isNotFoundError(err) {
-			return nil, fmt.Errorf("could not fetch CNAME records from zone '%s': %w", zone.Fqdn, err)
-		}
-		endpointsCNAME := ToCNAMEResponseMap(resC).ToEndpoints()
-		endpoints = append(endpoints, endpointsCNAME...)
-
-		var resT []ibclient.RecordTXT
-		objT := ibclient.NewEmptyRecordTXT()
-		objT.View = &p.config.View
-		objT.Zone = zone.Fqdn
-		err = PagingGetObject(p.client, objT, "", searchParams, &resT)
-		if err != nil && !isNotFoundError(err) {
-			return nil, fmt.Errorf("could not fetch TXT records from zone '%s': %w", zone.Fqdn, err)
-		}
-		endpointsTXT := ToTXTResponseMap(resT).ToEndpoints()
-		endpoints = append(endpoints, endpointsTXT...)
+		zoneEndpoints, err := p.zoneRecords(zone.Fqdn, AsInt64(zone.SoaDefaultTtl))
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, zoneEndpoints...)
 	}
 
 	log.Debugf("fetched %d records from infoblox", len(endpoints))
 	return endpoints, nil
 }
 
-func (p *Provider) AdjustEndpoints(endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
-	// Update user specified TTL (0 == disabled)
-	for _, ep := range endpoints {
-		if !ep.RecordTTL.IsConfigured() {
-			ep.RecordTTL = endpoint.TTL(p.config.DefaultTTL)
+// readClient returns the connector of the read-only grid member if configured
+func (p *Provider) readClient() ibclient.IBConnector {
+	if p.reader != nil {
+		return p.reader
+	}
+	return p.client
+}
+
+// zoneRecords gets the current records of a single zone. Records inheriting the TTL are reported
+// with the TTL of the zone, if it is known.
+func (p *Provider) zoneRecords(zone string, zoneTTL int64) (endpoints []*endpoint.Endpoint, err error) {
+	log.Debugf("fetch records from zone '%s'", zone)
+	searchParams := map[string]string{"zone": zone, "view": p.config.View}
+	var resA []ibclient.RecordA
+	objA := ibclient.NewEmptyRecordA()
+	objA.View = p.config.View
+	objA.Zone = zone
+	err = PagingGetObject(p.readClient(), objA, "", searchParams, &resA)
+	if err != nil && !isNotFoundError(err) {
+		return nil, newUpstreamError("", "", endpoint.RecordTypeA, zone, err)
+	}
+	for i := range resA {
+		inheritTTL(&resA[i].Ttl, resA[i].UseTtl, zoneTTL)
+	}
+	endpointsA := ToAResponseMap(resA).ToEndpoints()
+	endpoints = append(endpoints, endpointsA...)
+
+	// Include Host records since they should be treated synonymously with A records
+	var resH []ibclient.HostRecord
+	objH := ibclient.NewEmptyHostRecord()
+	objH.View = &p.config.View
+	objH.Zone = zone
+	err = PagingGetObject(p.readClient(), objH, "", searchParams, &resH)
+	if err != nil && !isNotFoundError(err) {
+		return nil, newUpstreamError("", "", "HOST", zone, err)
+	}
+	for i := range resH {
+		inheritTTL(&resH[i].Ttl, resH[i].UseTtl, zoneTTL)
+	}
+	endpointsHost := ToHostResponseMap(resH).ToEndpoints()
+	endpoints = append(endpoints, endpointsHost...)
+
+	var resC []ibclient.RecordCNAME
+	objC := ibclient.NewEmptyRecordCNAME()
+	objC.View = &p.config.View
+	objC.Zone = zone
+	err = PagingGetObject(p.readClient(), objC, "", searchParams, &resC)
+	if err != nil && !isNotFoundError(err) {
+		return nil, newUpstreamError("", "", endpoint.RecordTypeCNAME, zone, err)
+	}
+	for i := range resC {
+		inheritTTL(&resC[i].Ttl, resC[i].UseTtl, zoneTTL)
+	}
+	endpointsCNAME := ToCNAMEResponseMap(resC).ToEndpoints()
+	endpoints = append(endpoints, endpointsCNAME...)
+
+	var resT []ibclient.RecordTXT
+	objT := ibclient.NewEmptyRecordTXT()
+	objT.View = &p.config.View
+	objT.Zone = zone
+	err = PagingGetObject(p.readClient(), objT, "", searchParams, &resT)
+	if err != nil && !isNotFoundError(err) {
+		return nil, newUpstreamError("", "", endpoint.RecordTypeTXT, zone, err)
+	}
+	for i := range resT {
+		inheritTTL(&resT[i].Ttl, resT[i].UseTtl, zoneTTL)
+	}
+	endpointsTXT := ToTXTResponseMap(resT).ToEndpoints()
+	endpoints = append(endpoints, endpointsTXT...)
+
+	if p.config.OwnerEA != "" {
+		owners := map[string]string{}
+		for _, r := range resA {
+			p.addEAOwner(owners, endpoint.RecordTypeA, r.Name, r.Ea)
+		}
+		for _, r := range resH {
+			p.addEAOwner(owners, endpoint.RecordTypeA, r.Name, r.Ea)
+		}
+		for _, r := range resC {
+			p.addEAOwner(owners, endpoint.RecordTypeCNAME, r.Name, r.Ea)
+		}
+		for _, r := range resT {
+			p.addEAOwner(owners, endpoint.RecordTypeTXT, r.Name, r.Ea)
+		}
+		for _, ep := range endpoints {
+			if owner, ok := owners[ep.RecordType+"/"+ep.DNSName]; ok {
+				ep.Labels[endpoint.OwnerLabelKey] = owner
+			}
 		}
 	}
 
+	return endpoints, nil
+}
+
+// inheritTTL sets the TTL of a record inheriting it (use_ttl=false) to the TTL of the zone
+func inheritTTL(ttl **uint32, useTTL *bool, zoneTTL int64) {
+	if useTTL != nil && !*useTTL && zoneTTL > 0 {
+		inherited := uint32(zoneTTL)
+		*ttl = &inherited
+	}
+}
+
+// addEAOwner adds the owner of the record in the OwnerEA extensible attribute, keyed by type and name
+func (p *Provider) addEAOwner(owners map[string]string, recordType string, name *string, ea ibclient.EA) {
+	if value, ok := ea[p.config.OwnerEA]; ok {
+		owners[recordType+"/"+AsString(name)] = fmt.Sprint(value)
+	}
+}
+
+func (p *Provider) AdjustEndpoints(endpoints []*endpoint.Endpoint) ([]*endpoint.Endpoint, error) {
+	zones, err := p.endpointZones(endpoints)
+	if err != nil {
+		return nil, err
+	}
+	p.applyTTL(endpoints, zones)
+	p.flagProtected(endpoints, zones)
+
 	if !p.config.CreatePTR {
 		return endpoints, nil
 	}
@@ -309,53 +541,140 @@ func (p *Provider) submitChanges(changes []*infobloxChange) error {
 		return nil
 	}
 
-	zones, err := p.zones()
+	changesByZone, err := p.zoneChanges(changes)
 	if err != nil {
-		return fmt.Errorf("could not fetch zones: %w", err)
+		return err
 	}
 
-	changesByZone := p.ChangesByZone(zonePointerConverter(zones), changes)
-	for _, changes := range changesByZone {
-		for _, change := range changes {
-			record, err := p.buildRecord(change)
-			if err != nil {
-				return fmt.Errorf("could not build record: %w", err)
-			}
-			refId, logFields, err := getRefID(record)
-			if err != nil {
-				return err
-			}
-			logFields["action"] = change.Action
-			if p.config.DryRun {
-				log.WithFields(logFields).Info("Dry run: skipping..")
+	if err = p.queueForApproval(changesByZone); err != nil {
+		return err
+	}
+	return p.executeChanges(changesByZone)
+}
+
+// executeChanges sends the changes of each zone to Infoblox
+func (p *Provider) executeChanges(changesByZone map[string][]*infobloxChange) error {
+	var (
+		results []ChangeResult
+		failed  int
+	)
+	snap := p.newSnapshot()
+	defer snap.close()
+	defer func() {
+		sortChangeResults(results)
+		p.notifier.notify(p.config.View, results)
+	}()
+	zones := make([]string, 0, len(changesByZone))
+	for zone := range changesByZone {
+		zones = append(zones, zone)
+	}
+	sort.Strings(zones)
+	for _, zone := range zones {
+		for _, change := range changesByZone[zone] {
+			status, err := p.submitChange(zone, change, snap)
+			result := newChangeResult(zone, change, status, err)
+			countChange(result)
+			results = append(results, result)
+			if err == nil {
 				continue
 			}
-			log.WithFields(logFields).Info("Changing record")
-			switch change.Action {
-			case infobloxCreate:
-				_, err = p.client.CreateObject(record.obj)
-				if err != nil {
-					return err
-				}
-			case infobloxDelete:
-				_, err = p.client.DeleteObject(refId)
-				if err != nil {
-					return err
-				}
-			case infobloxUpdate:
-				_, err = p.client.UpdateObject(record.obj, refId)
-				if err != nil {
-					return err
-				}
-			default:
-				return fmt.Errorf("unknown action '%s'", change.Action)
+			if !p.config.ContinueOnError {
+				return err
 			}
+			log.WithFields(log.Fields{
+				"action": change.Action,
+				"record": change.Endpoint.DNSName,
+				"type":   change.Endpoint.RecordType,
+				"zone":   zone,
+			}).WithError(err).Error("Failed to change record, continuing with remaining changes")
+			failed++
 		}
 	}
 
+	if failed > 0 {
+		log.Errorf("%d of %d changes failed", failed, len(results))
+		// the results are sorted in place by the deferred function
+		return NewApplyError(results)
+	}
 	return nil
 }
 
+// zoneChanges groups the changes by zone and applies the policy and the deletion thresholds to them
+func (p *Provider) zoneChanges(changes []*infobloxChange) (map[string][]*infobloxChange, error) {
+	zones, err := p.zones()
+	if err != nil {
+		return nil, fmt.Errorf("could not fetch zones: %w", err)
+	}
+	changesByZone := p.ChangesByZone(zonePointerConverter(zones), changes)
+	if err = p.enforcePolicy(changesByZone); err != nil {
+		return nil, err
+	}
+	if err = p.checkDeletionThresholds(changesByZone); err != nil {
+		return nil, err
+	}
+	return changesByZone, nil
+}
+
+// translateChange builds the record of the change and looks up the ref of the object it changes
+func (p *Provider) translateChange(zone string, change *infobloxChange) (*infobloxRecordSet, string, log.Fields, error) {
+	record, err := p.buildRecord(change)
+	if err != nil {
+		return nil, "", nil, changeError(change, zone, err)
+	}
+	change.settings.apply(record.obj)
+	refId, logFields, err := getRefID(record)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	logFields["action"] = change.Action
+	return record, refId, logFields, nil
+}
+
+// submitChange sends a single change to Infoblox and returns its ChangeStatus.
+// The current state of the record is stored in the snapshot before it is changed.
+func (p *Provider) submitChange(zone string, change *infobloxChange, snap *snapshot) (ChangeStatus, error) {
+	record, refId, logFields, err := p.translateChange(zone, change)
+	if err != nil {
+		return ChangeStatusFailed, err
+	}
+	if p.config.DryRun {
+		log.WithFields(logFields).Info("Dry run: skipping..")
+		return ChangeStatusSkipped, nil
+	}
+	if err = snap.add(zone, p.config.View, change, record, refId); err != nil {
+		return ChangeStatusFailed, fmt.Errorf("could not snapshot %s record '%s', refusing to change it: %w",
+			change.Endpoint.RecordType, change.Endpoint.DNSName, err)
+	}
+	log.WithFields(logFields).Info("Changing record")
+	ref := refId
+	switch change.Action {
+	case infobloxCreate:
+		ref, err = p.client.CreateObject(record.obj)
+	case infobloxDelete:
+		_, err = p.client.DeleteObject(refId)
+	case infobloxUpdate:
+		_, err = p.client.UpdateObject(record.obj, refId)
+	default:
+		return ChangeStatusFailed, fmt.Errorf("unknown action '%s'", change.Action)
+	}
+	p.audit.write(newAuditEntry(zone, p.config.View, change, record, ref, err))
+	if err != nil {
+		return ChangeStatusFailed, changeError(change, zone, err)
+	}
+	return ChangeStatusApplied, nil
+}
+
+// changeError annotates the error of a change with the record and zone it failed on
+func changeError(change *infobloxChange, zone string, err error) error {
+	var recordErr *RecordError
+	if errors.As(err, &recordErr) && recordErr.Kind == ErrorKindUnsupported {
+		recordErr.Action = change.Action
+		recordErr.Zone = zone
+		return recordErr
+	}
+	return newUpstreamError(change.Action, change.Endpoint.DNSName, change.Endpoint.RecordType, zone, err)
+}
+
 func getRefID(record *infobloxRecordSet) (string, log.Fields, error) {
 	t := reflect.TypeOf(record.obj).Elem().Name()
 	l := log.Fields{
@@ -500,7 +819,11 @@ func (p *Provider) CountDiff(changes *plan.Changes) {
 
 // ApplyChanges applies the given changes.
 func (p *Provider) ApplyChanges(_ context.Context, changes *plan.Changes) error {
+	return p.submitChanges(p.combineChanges(changes))
+}
 
+// combineChanges splits the plan into changes of single targets
+func (p *Provider) combineChanges(changes *plan.Changes) []*infobloxChange {
 	p.CountDiff(changes)
 
 	combinedChanges := make([]*infobloxChange, 0, len(changes.Create)+len(changes.UpdateNew)+len(changes.Delete))
@@ -509,7 +832,20 @@ func (p *Provider) ApplyChanges(_ context.Context, changes *plan.Changes) error
 	combinedChanges = append(combinedChanges, newIBChanges(infobloxUpdate, changes.UpdateNew)...)
 	combinedChanges = append(combinedChanges, newIBChanges(infobloxDelete, changes.Delete)...)
 
-	return p.submitChanges(combinedChanges)
+	return combinedChanges
+}
+
+// Zones returns the names of the authoritative zones matching the domain filter
+func (p *Provider) Zones() ([]string, error) {
+	zones, err := p.zones()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(zones))
+	for _, zone := range zones {
+		names = append(names, zone.Fqdn)
+	}
+	return names, nil
 }
 
 func (p *Provider) zones() ([]ibclient.ZoneAuth, error) {
@@ -519,10 +855,11 @@ func (p *Provider) zones() ([]ibclient.ZoneAuth, error) {
 			View: &p.config.View,
 		},
 	)
+	obj.SetReturnFields(append(obj.ReturnFields(), "soa_default_ttl"))
 	queryParams := recordQueryParams("", p.config.View)
 	err := p.client.GetObject(obj, "", queryParams, &res)
 	if err != nil && !isNotFoundError(err) {
-		return nil, err
+		return nil, &RecordError{Kind: ErrorKindUpstream, Err: err}
 	}
 
 	for _, zone := range res {
@@ -544,6 +881,10 @@ func (p *Provider) zones() ([]ibclient.ZoneAuth, error) {
 type infobloxChange struct {
 	Action   string
 	Endpoint *endpoint.Endpoint
+	// settings restore the record settings of a snapshot, nil for the changes of external-dns
+	settings *RecordSettings
+	// derivedFrom is the A change a PTR change is derived from by INFOBLOX_CREATE_PTR, nil for other changes
+	derivedFrom *infobloxChange
 }
 
 func (p *Provider) ChangesByZone(zones []*ibclient.ZoneAuth, changeSets []*infobloxChange) map[string][]*infobloxChange {
@@ -568,7 +909,7 @@ func (p *Provider) ChangesByZone(zones []*ibclient.ZoneAuth, changeSets []*infob
 			}
 			copyEp := *c.Endpoint
 			copyEp.RecordType = endpoint.RecordTypePTR
-			changes[reverseZone.Fqdn] = append(changes[reverseZone.Fqdn], &infobloxChange{c.Action, &copyEp})
+			changes[reverseZone.Fqdn] = append(changes[reverseZone.Fqdn], &infobloxChange{Action: c.Action, Endpoint: &copyEp, derivedFrom: c})
 		}
 	}
 	return changes
@@ -616,11 +957,7 @@ func (p *Provider) findReverseZone(zones []*ibclient.ZoneAuth, name string) *ibc
 }
 
 func (p *Provider) recordSet(ep *endpoint.Endpoint, getObject bool) (recordSet infobloxRecordSet, err error) {
-	var ttl uint32
-	if ep.RecordTTL.IsConfigured() {
-		ttl = uint32(ep.RecordTTL)
-	}
-	ptrToBoolTrue := true
+	ttl, useTTL := p.recordTTL(ep)
 	switch ep.RecordType {
 	case endpoint.RecordTypeA:
 		var res []ibclient.RecordA
@@ -628,8 +965,8 @@ func (p *Provider) recordSet(ep *endpoint.Endpoint, getObject bool) (recordSet i
 		obj.Name = &ep.DNSName
 		// TODO: get target index
 		obj.Ipv4Addr = &ep.Targets[0]
-		obj.Ttl = &ttl
-		obj.UseTtl = &ptrToBoolTrue
+		obj.Ttl = ttl
+		obj.UseTtl = useTTL
 		if getObject {
 			queryParams := ibclient.NewQueryParams(false, map[string]string{"name": *obj.Name, "ipv4addr": *obj.Ipv4Addr})
 			err = p.client.GetObject(obj, "", queryParams, &res)
@@ -648,8 +985,8 @@ func (p *Provider) recordSet(ep *endpoint.Endpoint, getObject bool) (recordSet i
 		obj.PtrdName = &ep.DNSName
 		// TODO: get target index
 		obj.Ipv4Addr = &ep.Targets[0]
-		obj.Ttl = &ttl
-		obj.UseTtl = &ptrToBoolTrue
+		obj.Ttl = ttl
+		obj.UseTtl = useTTL
 		if getObject {
 			queryParams := ibclient.NewQueryParams(false, map[string]string{"name": *obj.PtrdName})
 			err = p.client.GetObject(obj, "", queryParams, &res)
@@ -666,8 +1003,8 @@ func (p *Provider) recordSet(ep *endpoint.Endpoint, getObject bool) (recordSet i
 		obj := ibclient.NewEmptyRecordCNAME()
 		obj.Name = &ep.DNSName
 		obj.Canonical = &ep.Targets[0]
-		obj.Ttl = &ttl
-		obj.UseTtl = &ptrToBoolTrue
+		obj.Ttl = ttl
+		obj.UseTtl = useTTL
 		if getObject {
 			queryParams := ibclient.NewQueryParams(false, map[string]string{"name": *obj.Name})
 			err = p.client.GetObject(obj, "", queryParams, &res)
@@ -689,8 +1026,8 @@ func (p *Provider) recordSet(ep *endpoint.Endpoint, getObject bool) (recordSet i
 		obj := ibclient.NewEmptyRecordTXT()
 		obj.Text = &ep.Targets[0]
 		obj.Name = &ep.DNSName
-		obj.Ttl = &ttl
-		obj.UseTtl = &ptrToBoolTrue
+		obj.Ttl = ttl
+		obj.UseTtl = useTTL
 		// TODO: Zone?
 		if getObject {
 			queryParams := ibclient.NewQueryParams(false, map[string]string{"name": *obj.Name})
@@ -703,10 +1040,32 @@ func (p *Provider) recordSet(ep *endpoint.Endpoint, getObject bool) (recordSet i
 			obj: obj,
 			res: &res,
 		}
+	default:
+		err = &RecordError{
+			Kind:       ErrorKindUnsupported,
+			Name:       ep.DNSName,
+			RecordType: ep.RecordType,
+			Err:        fmt.Errorf("record type '%s' is not supported", ep.RecordType),
+		}
 	}
 	return
 }
 
+// recordTTL returns the ttl and use_ttl of the record of the endpoint. With INFOBLOX_INHERIT_TTL,
+// the records of endpoints without a TTL inherit the TTL of the zone.
+func (p *Provider) recordTTL(ep *endpoint.Endpoint) (*uint32, *bool) {
+	useTTL := true
+	if !ep.RecordTTL.IsConfigured() && p.config.InheritTTL {
+		useTTL = false
+		return nil, &useTTL
+	}
+	var ttl uint32
+	if ep.RecordTTL.IsConfigured() {
+		ttl = uint32(ep.RecordTTL)
+	}
+	return &ttl, &useTTL
+}
+
 func (p *Provider) buildRecord(change *infobloxChange) (*infobloxRecordSet, error) {
 	rs, err := p.recordSet(change.Endpoint, !(change.Action == infobloxCreate))
 	if err != nil {
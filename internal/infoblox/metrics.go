@@ -0,0 +1,39 @@
+package infoblox
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// changesTotal counts the changes submitted to Infoblox by their outcome
+var changesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "infoblox_webhook",
+	Name:      "changes_total",
+	Help:      "Number of record changes submitted to Infoblox, by action, record type, zone and status.",
+}, []string{"action", "type", "zone", "status"})
+
+func init() {
+	prometheus.MustRegister(changesTotal)
+}
+
+// countChange counts the outcome of the change
+func countChange(result ChangeResult) {
+	changesTotal.WithLabelValues(result.Action, result.RecordType, result.Zone, string(result.Status)).Inc()
+}
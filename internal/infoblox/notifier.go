@@ -0,0 +1,177 @@
+package infoblox
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// defaultNotifyTemplate renders a payload accepted by Slack and Teams incoming webhooks,
+// extended with the list of changes for generic receivers
+const defaultNotifyTemplate = `{
+  "text": {{ printf "%d DNS records changed in view %s" (len .Changes) .View | json }},
+  "changes": {{ json .Changes }}
+}`
+
+// Notification is the data passed to the notification template
+type Notification struct {
+	Time    time.Time        `json:"time"`
+	View    string           `json:"view"`
+	Changes []NotifiedChange `json:"changes"`
+}
+
+// NotifiedChange is a single applied change of a Notification
+type NotifiedChange struct {
+	Action     string `json:"action"`
+	Name       string `json:"name"`
+	RecordType string `json:"recordType"`
+	Target     string `json:"target,omitempty"`
+	Zone       string `json:"zone"`
+}
+
+// notifier posts a Notification of the changes applied by a plan to the configured URLs
+type notifier struct {
+	urls        []string
+	template    *template.Template
+	zones       []string
+	recordTypes []string
+	actions     []string
+	retries     int
+	backoff     time.Duration
+	client      *http.Client
+	wg          sync.WaitGroup
+}
+
+func newNotifier(cfg *StartupConfig) (*notifier, error) {
+	text := defaultNotifyTemplate
+	if cfg.NotifyTemplate != "" {
+		data, err := os.ReadFile(cfg.NotifyTemplate)
+		if err != nil {
+			return nil, fmt.Errorf("could not read notification template: %w", err)
+		}
+		text = string(data)
+	}
+	tmpl, err := template.New("notification").Funcs(template.FuncMap{"json": toJSON}).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse notification template: %w", err)
+	}
+	return &notifier{
+		urls:        cfg.NotifyURLs,
+		template:    tmpl,
+		zones:       cfg.NotifyZones,
+		recordTypes: cfg.NotifyRecordTypes,
+		actions:     cfg.NotifyActions,
+		retries:     cfg.NotifyRetries,
+		backoff:     time.Second,
+		client:      &http.Client{Timeout: cfg.NotifyTimeout},
+	}, nil
+}
+
+func toJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	return string(data), err
+}
+
+func matchesFilter(filter []string, value string) bool {
+	return len(filter) == 0 || slices.ContainsFunc(filter, func(f string) bool { return strings.EqualFold(f, value) })
+}
+
+// notify sends the applied changes matching the filters in the background
+func (n *notifier) notify(view string, results []ChangeResult) {
+	if n == nil {
+		return
+	}
+	notification := Notification{Time: time.Now().UTC(), View: view}
+	for _, result := range results {
+		if result.Status != ChangeStatusApplied ||
+			!matchesFilter(n.zones, result.Zone) ||
+			!matchesFilter(n.recordTypes, result.RecordType) ||
+			!matchesFilter(n.actions, result.Action) {
+			continue
+		}
+		notification.Changes = append(notification.Changes, NotifiedChange{
+			Action:     result.Action,
+			Name:       result.Name,
+			RecordType: result.RecordType,
+			Target:     result.Target,
+			Zone:       result.Zone,
+		})
+	}
+	if len(notification.Changes) == 0 {
+		return
+	}
+
+	payload := &bytes.Buffer{}
+	if err := n.template.Execute(payload, notification); err != nil {
+		log.WithError(err).Error("could not render notification")
+		return
+	}
+	for _, url := range n.urls {
+		n.wg.Add(1)
+		go func(url string) {
+			defer n.wg.Done()
+			if err := n.post(url, payload.Bytes()); err != nil {
+				log.WithError(err).Errorf("could not notify '%s' of %d changes", url, len(notification.Changes))
+			}
+		}(url)
+	}
+}
+
+// post sends the payload, retrying failed requests and responses with status 429 or 5xx
+func (n *notifier) post(url string, payload []byte) (err error) {
+	backoff := n.backoff
+	for attempt := 0; ; attempt++ {
+		var resp *http.Response
+		resp, err = n.client.Post(url, "application/json", bytes.NewReader(payload))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode < 300 {
+				return nil
+			}
+			err = fmt.Errorf("unexpected status %s", resp.Status)
+			if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+				return err
+			}
+		}
+		if attempt >= n.retries {
+			return err
+		}
+		log.WithError(err).Debugf("notifying '%s' failed, retrying in %s", url, backoff)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// wait blocks until the pending notifications are sent
+func (n *notifier) wait() {
+	if n != nil {
+		n.wg.Wait()
+	}
+}
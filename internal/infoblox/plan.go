@@ -0,0 +1,80 @@
+package infoblox
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"context"
+	"slices"
+
+	"sigs.k8s.io/external-dns/plan"
+
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/pkg/webhook"
+)
+
+// Plan translates the changes into the WAPI operations ApplyChanges would send, without changing
+// anything in Infoblox. The policy and the deletion thresholds are enforced like in ApplyChanges,
+// so a plan which would be refused fails with the same error. Operations which ApplyChanges would
+// queue for approval are marked as queued.
+func (p *Provider) Plan(_ context.Context, changes *plan.Changes) ([]webhook.Operation, error) {
+	operations := []webhook.Operation{}
+	combinedChanges := p.combineChanges(changes)
+	if len(combinedChanges) == 0 {
+		return operations, nil
+	}
+	changesByZone, err := p.zoneChanges(combinedChanges)
+	if err != nil {
+		return nil, err
+	}
+	var queue map[*infobloxChange]string
+	if p.approvals != nil {
+		queue = p.approvalChanges(changesByZone)
+	}
+
+	zones := make([]string, 0, len(changesByZone))
+	for zone := range changesByZone {
+		zones = append(zones, zone)
+	}
+	slices.Sort(zones)
+	for _, zone := range zones {
+		for _, change := range changesByZone[zone] {
+			record, ref, _, err := p.translateChange(zone, change)
+			if err != nil {
+				return nil, err
+			}
+			target, ttl := endpointValues(record.obj)
+			_, queued := queue[change]
+			if change.derivedFrom != nil {
+				_, queued = queue[change.derivedFrom]
+			}
+			operations = append(operations, webhook.Operation{
+				Action:     change.Action,
+				Object:     record.obj.ObjectType(),
+				Name:       change.Endpoint.DNSName,
+				RecordType: change.Endpoint.RecordType,
+				Target:     target,
+				TTL:        ttl,
+				Zone:       zone,
+				Ref:        ref,
+				Reverse:    change.derivedFrom != nil,
+				Queued:     queued,
+			})
+		}
+	}
+	return operations, nil
+}
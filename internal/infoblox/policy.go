@@ -0,0 +1,367 @@
+package infoblox
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+	"sigs.k8s.io/external-dns/endpoint"
+)
+
+const (
+	// PolicyActionReject fails the whole plan when it touches a protected record
+	PolicyActionReject = "reject"
+	// PolicyActionSkip drops the changes of protected records from the plan
+	PolicyActionSkip = "skip"
+)
+
+// Policy is read from INFOBLOX_POLICY_FILE or INFOBLOX_POLICY (YAML or JSON)
+type Policy struct {
+	// ProtectedAction is applied to changes of protected records, reject (default) or skip
+	ProtectedAction string          `yaml:"protectedAction"`
+	Protected       []ProtectedRule `yaml:"protected"`
+	// TTL rules set the TTL of endpoints, the first matching rule applies
+	TTL []TTLRule `yaml:"ttl"`
+}
+
+// RecordSelector matches records by name, type and zone. All configured criteria must match.
+type RecordSelector struct {
+	// Name is a regular expression matched against the record name
+	Name        string   `yaml:"name"`
+	RecordTypes []string `yaml:"recordTypes"`
+	// Zones restricts the rule to records of the listed zones, not of their sub-zones managed as zones of their own
+	Zones []string `yaml:"zones"`
+	// Apex matches only records at the apex of a zone
+	Apex bool `yaml:"apex"`
+
+	nameRegEx *regexp.Regexp
+}
+
+// ProtectedRule matches records which must not be changed by the webhook
+type ProtectedRule = RecordSelector
+
+// TTLRule sets the TTL of the endpoints it selects. Default replaces INFOBLOX_DEFAULT_TTL for
+// endpoints without a TTL, Min and Max clamp any TTL set, including the TTL set by the user. 0 disables them.
+type TTLRule struct {
+	RecordSelector `yaml:",inline"`
+	Default        int `yaml:"default"`
+	Min            int `yaml:"min"`
+	Max            int `yaml:"max"`
+}
+
+// LoadPolicy reads and validates the policy file
+func LoadPolicy(path string) (*Policy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read policy file: %w", err)
+	}
+	policy, err := ParsePolicy(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid policy file '%s': %w", path, err)
+	}
+	return policy, nil
+}
+
+// ParsePolicy parses and validates the policy
+func ParsePolicy(data []byte) (*Policy, error) {
+	policy := &Policy{}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(policy); err != nil {
+		return nil, fmt.Errorf("could not parse policy: %w", err)
+	}
+	if err := policy.init(); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func (p *Policy) init() error {
+	switch p.ProtectedAction {
+	case "":
+		p.ProtectedAction = PolicyActionReject
+	case PolicyActionReject, PolicyActionSkip:
+	default:
+		return fmt.Errorf("unknown protectedAction '%s', expected '%s' or '%s'", p.ProtectedAction, PolicyActionReject, PolicyActionSkip)
+	}
+	for i := range p.Protected {
+		rule := &p.Protected[i]
+		if rule.Name == "" && len(rule.RecordTypes) == 0 && len(rule.Zones) == 0 && !rule.Apex {
+			return fmt.Errorf("protected rule %d has no criteria and would match every record", i+1)
+		}
+		if err := rule.init(); err != nil {
+			return fmt.Errorf("protected rule %d: %w", i+1, err)
+		}
+	}
+	for i := range p.TTL {
+		rule := &p.TTL[i]
+		if err := rule.init(); err != nil {
+			return fmt.Errorf("ttl rule %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+func (r *RecordSelector) init() error {
+	if r.Name != "" {
+		re, err := regexp.Compile(r.Name)
+		if err != nil {
+			return fmt.Errorf("invalid name regexp '%s': %w", r.Name, err)
+		}
+		r.nameRegEx = re
+	}
+	return nil
+}
+
+func (r *TTLRule) init() error {
+	switch {
+	case r.Default < 0 || r.Min < 0 || r.Max < 0:
+		return fmt.Errorf("negative TTL")
+	case r.Default == 0 && r.Min == 0 && r.Max == 0:
+		return fmt.Errorf("none of default, min and max is set")
+	case r.Max > 0 && r.Min > r.Max:
+		return fmt.Errorf("min %d is greater than max %d", r.Min, r.Max)
+	case r.Default > 0 && (r.Default < r.Min || r.Max > 0 && r.Default > r.Max):
+		return fmt.Errorf("default %d is out of the range of min and max", r.Default)
+	}
+	return r.RecordSelector.init()
+}
+
+// ttl returns the TTL of the endpoint: the default of the rule or defaultTTL if the endpoint
+// has no TTL, clamped by the min and max of the rule. Without any of them, the endpoint keeps having no TTL.
+func (r *TTLRule) ttl(ep *endpoint.Endpoint, defaultTTL int) endpoint.TTL {
+	ttl := ep.RecordTTL
+	if !ttl.IsConfigured() {
+		ttl = endpoint.TTL(defaultTTL)
+		if r.Default > 0 {
+			ttl = endpoint.TTL(r.Default)
+		}
+		if !ttl.IsConfigured() {
+			return ttl
+		}
+	}
+	if r.Min > 0 && ttl < endpoint.TTL(r.Min) {
+		ttl = endpoint.TTL(r.Min)
+	}
+	if r.Max > 0 && ttl > endpoint.TTL(r.Max) {
+		ttl = endpoint.TTL(r.Max)
+	}
+	return ttl
+}
+
+// ttlRule returns the first TTL rule matching the record of the zone or nil
+func (p *Policy) ttlRule(name, recordType, zone string) *TTLRule {
+	if p == nil {
+		return nil
+	}
+	for i := range p.TTL {
+		if p.TTL[i].matches(name, recordType, zone) {
+			return &p.TTL[i]
+		}
+	}
+	return nil
+}
+
+// protectedBy returns the rule protecting the record of the zone or nil
+func (p *Policy) protectedBy(name, recordType, zone string) *ProtectedRule {
+	if p == nil {
+		return nil
+	}
+	for i := range p.Protected {
+		if p.Protected[i].matches(name, recordType, zone) {
+			return &p.Protected[i]
+		}
+	}
+	return nil
+}
+
+func (r *RecordSelector) matches(name, recordType, zone string) bool {
+	if r.nameRegEx != nil && !r.nameRegEx.MatchString(name) {
+		return false
+	}
+	if len(r.RecordTypes) > 0 && !slices.ContainsFunc(r.RecordTypes, func(t string) bool { return strings.EqualFold(t, recordType) }) {
+		return false
+	}
+	// the zone of the record is matched, records of sub-zones managed as zones of their own don't match
+	if len(r.Zones) > 0 && !slices.ContainsFunc(r.Zones, func(z string) bool { return strings.EqualFold(strings.TrimSuffix(z, "."), zone) }) {
+		return false
+	}
+	if r.Apex && (zone == "" || !strings.EqualFold(name, zone)) {
+		return false
+	}
+	return true
+}
+
+func (r *RecordSelector) String() string {
+	var criteria []string
+	if r.Name != "" {
+		criteria = append(criteria, fmt.Sprintf("name=%s", r.Name))
+	}
+	if len(r.RecordTypes) > 0 {
+		criteria = append(criteria, fmt.Sprintf("recordTypes=%s", strings.Join(r.RecordTypes, ",")))
+	}
+	if len(r.Zones) > 0 {
+		criteria = append(criteria, fmt.Sprintf("zones=%s", strings.Join(r.Zones, ",")))
+	}
+	if r.Apex {
+		criteria = append(criteria, "apex")
+	}
+	return strings.Join(criteria, " ")
+}
+
+// InZone returns true if the name is the zone itself or below it
+func InZone(name, zone string) bool {
+	name, zone = strings.ToLower(strings.TrimSuffix(name, ".")), strings.ToLower(strings.TrimSuffix(zone, "."))
+	return name == zone || strings.HasSuffix(name, "."+zone)
+}
+
+// ZoneOf returns the most specific of the zones containing the name, empty if there is none
+func ZoneOf(zones []string, name string) (result string) {
+	for _, zone := range zones {
+		if InZone(name, zone) && len(zone) > len(result) {
+			result = zone
+		}
+	}
+	return result
+}
+
+// enforcePolicy rejects the plan or drops the changes touching protected records. PTR changes derived
+// from A changes by INFOBLOX_CREATE_PTR are protected by the rules of their A record as well.
+func (p *Provider) enforcePolicy(changesByZone map[string][]*infobloxChange) error {
+	if p.policy == nil {
+		return nil
+	}
+	zoneOf := map[*infobloxChange]string{}
+	for zone, changes := range changesByZone {
+		for _, change := range changes {
+			zoneOf[change] = zone
+		}
+	}
+	for zone, changes := range changesByZone {
+		allowed := changes[:0]
+		for _, change := range changes {
+			rule := p.policy.protectedBy(change.Endpoint.DNSName, change.Endpoint.RecordType, zone)
+			if source := change.derivedFrom; rule == nil && source != nil {
+				rule = p.policy.protectedBy(source.Endpoint.DNSName, source.Endpoint.RecordType, zoneOf[source])
+			}
+			if rule == nil {
+				allowed = append(allowed, change)
+				continue
+			}
+			logFields := log.Fields{
+				"action": change.Action,
+				"record": change.Endpoint.DNSName,
+				"type":   change.Endpoint.RecordType,
+				"zone":   zone,
+				"rule":   rule.String(),
+			}
+			if p.policy.ProtectedAction == PolicyActionSkip {
+				log.WithFields(logFields).Warn("Skipping change of protected record")
+				continue
+			}
+			log.WithFields(logFields).Error("Rejecting plan changing protected record")
+			return &RecordError{
+				Kind:       ErrorKindRejected,
+				Action:     change.Action,
+				Name:       change.Endpoint.DNSName,
+				RecordType: change.Endpoint.RecordType,
+				Zone:       zone,
+				Err:        fmt.Errorf("record is protected by policy rule '%s'", rule),
+			}
+		}
+		changesByZone[zone] = allowed
+	}
+	return nil
+}
+
+// endpointZones returns the zones of the endpoints, found like the zones of their changes, so the policy
+// matches the same zones when the endpoints are adjusted and when their changes are applied.
+// The zones are only looked up if there is a policy, the zone of endpoints in none of the zones is empty.
+func (p *Provider) endpointZones(endpoints []*endpoint.Endpoint) ([]string, error) {
+	result := make([]string, len(endpoints))
+	if p.policy == nil {
+		return result, nil
+	}
+	zones, err := p.zones()
+	if err != nil {
+		return nil, err
+	}
+	pointers := zonePointerConverter(zones)
+	for i, ep := range endpoints {
+		if zone := p.findZone(pointers, ep.DNSName); zone != nil {
+			result[i] = zone.Fqdn
+		}
+	}
+	return result, nil
+}
+
+// applyTTL sets the TTL of the endpoints by the TTL rules, endpoints matching no rule without a TTL
+// get INFOBLOX_DEFAULT_TTL, unless they inherit the TTL of the zone with INFOBLOX_INHERIT_TTL
+func (p *Provider) applyTTL(endpoints []*endpoint.Endpoint, zones []string) {
+	defaultTTL := p.config.DefaultTTL
+	if p.config.InheritTTL {
+		defaultTTL = 0
+	}
+	for i, ep := range endpoints {
+		rule := p.policy.ttlRule(ep.DNSName, ep.RecordType, zones[i])
+		if rule == nil {
+			if !ep.RecordTTL.IsConfigured() {
+				ep.RecordTTL = endpoint.TTL(defaultTTL)
+			}
+			continue
+		}
+		ttl := rule.ttl(ep, defaultTTL)
+		if ep.RecordTTL.IsConfigured() && ttl != ep.RecordTTL {
+			log.WithFields(log.Fields{
+				"record": ep.DNSName,
+				"type":   ep.RecordType,
+				"rule":   rule.String(),
+			}).Debugf("TTL %d is out of the range of the TTL rule, using %d", ep.RecordTTL, ttl)
+		}
+		ep.RecordTTL = ttl
+	}
+}
+
+// flagProtected reports the endpoints whose changes will be refused by the policy. It runs on every sync,
+// so it only logs at debug level, the refused changes are logged by enforcePolicy.
+func (p *Provider) flagProtected(endpoints []*endpoint.Endpoint, zones []string) {
+	if p.policy == nil {
+		return
+	}
+	outcome := "rejected"
+	if p.policy.ProtectedAction == PolicyActionSkip {
+		outcome = "skipped"
+	}
+	for i, ep := range endpoints {
+		if rule := p.policy.protectedBy(ep.DNSName, ep.RecordType, zones[i]); rule != nil {
+			log.WithFields(log.Fields{
+				"record": ep.DNSName,
+				"type":   ep.RecordType,
+				"rule":   rule.String(),
+			}).Debugf("Endpoint is protected by policy, its changes will be %s", outcome)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package infoblox
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	ibclient "github.com/infobloxopen/infoblox-go-client/v2"
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/publicsuffix"
+)
+
+// errFingerprintMismatch is returned when the certificate of the grid master matches none of the pinned fingerprints
+var errFingerprintMismatch = errors.New("certificate fingerprint matches none of INFOBLOX_SERVER_FINGERPRINTS")
+
+// httpRequestor is the HttpRequestor of the WAPI connector. Unlike ibclient.WapiHttpRequestor,
+// it verifies the grid master by the CA bundle and the pinned certificate fingerprints,
+// explains the TLS verification failures and connects through the configured proxy.
+type httpRequestor struct {
+	tlsConfig *tls.Config
+	proxy     func(*http.Request) (*url.URL, error)
+	client    http.Client
+}
+
+// newHTTPRequestor prepares the TLS configuration, the HTTP client is created by Init
+func newHTTPRequestor(cfg *StartupConfig) (*httpRequestor, error) {
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: !cfg.SSLVerify,
+		Renegotiation:      tls.RenegotiateOnceAsClient,
+	}
+	if cfg.CAFile != "" {
+		data, err := os.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA bundle: %w", err)
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no PEM encoded certificates found in CA bundle '%s'", cfg.CAFile)
+		}
+	}
+	if len(cfg.ServerFingerprints) > 0 {
+		fingerprints := make([]string, 0, len(cfg.ServerFingerprints))
+		for _, f := range cfg.ServerFingerprints {
+			f = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(f), ":", ""))
+			if b, err := hex.DecodeString(f); err != nil || len(b) != sha256.Size {
+				return nil, fmt.Errorf("invalid server fingerprint '%s', expected a hex encoded SHA-256 hash", f)
+			}
+			fingerprints = append(fingerprints, f)
+		}
+		tlsConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return errFingerprintMismatch
+			}
+			sum := sha256.Sum256(cs.PeerCertificates[0].Raw)
+			if !slices.Contains(fingerprints, hex.EncodeToString(sum[:])) {
+				return fmt.Errorf("%w: %X", errFingerprintMismatch, sum)
+			}
+			return nil
+		}
+	}
+	proxy, err := newProxyFunc(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &httpRequestor{tlsConfig: tlsConfig, proxy: proxy}, nil
+}
+
+// newProxyFunc returns the proxy of INFOBLOX_PROXY_URL, or of the HTTPS_PROXY and NO_PROXY environment
+// variables if unset. Proxy credentials are passed as user info of the URL.
+func newProxyFunc(cfg *StartupConfig) (func(*http.Request) (*url.URL, error), error) {
+	if cfg.ProxyURL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+	u, err := url.Parse(cfg.ProxyURL)
+	if err != nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL, expected e.g. http://proxy:3128")
+	}
+	proxyConfig := &httpproxy.Config{
+		HTTPProxy:  cfg.ProxyURL,
+		HTTPSProxy: cfg.ProxyURL,
+		NoProxy:    strings.Join(cfg.NoProxy, ","),
+	}
+	proxyFunc := proxyConfig.ProxyFunc()
+	return func(req *http.Request) (*url.URL, error) {
+		return proxyFunc(req.URL)
+	}, nil
+}
+
+// Init creates the HTTP client, authenticating by the client certificate of authCfg.
+// The key pair was validated by newAuthConfig.
+func (r *httpRequestor) Init(authCfg ibclient.AuthConfig, trCfg ibclient.TransportConfig) {
+	tlsConfig := r.tlsConfig.Clone()
+	if authCfg.ClientCert != nil && authCfg.ClientKey != nil {
+		if cert, err := tls.X509KeyPair(authCfg.ClientCert, authCfg.ClientKey); err == nil {
+			tlsConfig.Certificates = []tls.Certificate{cert}
+		}
+	}
+	transport := &http.Transport{
+		TLSClientConfig:     tlsConfig,
+		MaxIdleConnsPerHost: trCfg.HttpPoolConnections,
+		Proxy:               r.proxy,
+	}
+	// WAPI keeps the session in the ibapauth cookie
+	jar, _ := cookiejar.New(&cookiejar.Options{PublicSuffixList: publicsuffix.List})
+	r.client = http.Client{
+		Jar:       jar,
+		Transport: transport,
+		Timeout:   trCfg.HttpRequestTimeout * time.Second,
+	}
+}
+
+// SendRequest sends the request and returns the body of a successful response
+func (r *httpRequestor) SendRequest(req *http.Request) ([]byte, error) {
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, explainTLSError(req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK && (resp.StatusCode != http.StatusCreated || req.Method != http.MethodPost) {
+		msg := fmt.Sprintf("WAPI request error: %d('%s')\nContents:\n%s\n", resp.StatusCode, resp.Status, body)
+		if resp.StatusCode == http.StatusNotFound {
+			return nil, ibclient.NewNotFoundError(msg)
+		}
+		return nil, errors.New(msg)
+	}
+	return body, nil
+}
+
+// explainTLSError names the reason the certificate of the grid master was not accepted
+func explainTLSError(host string, err error) error {
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		hostname         x509.HostnameError
+		invalid          x509.CertificateInvalidError
+	)
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return fmt.Errorf("certificate of grid master '%s' is signed by an unknown authority, "+
+			"set INFOBLOX_CA_FILE to the CA bundle of the grid: %w", host, err)
+	case errors.As(err, &hostname):
+		return fmt.Errorf("certificate of grid master '%s' is not valid for the host name: %w", host, err)
+	case errors.As(err, &invalid):
+		return fmt.Errorf("certificate of grid master '%s' is invalid: %w", host, err)
+	case errors.Is(err, errFingerprintMismatch):
+		return fmt.Errorf("certificate of grid master '%s' is not pinned: %w", host, err)
+	}
+	return err
+}
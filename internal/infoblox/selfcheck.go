@@ -0,0 +1,124 @@
+package infoblox
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	ibclient "github.com/infobloxopen/infoblox-go-client/v2"
+	log "github.com/sirupsen/logrus"
+)
+
+// wapiSchema is the response of the WAPI schema request (GET /wapi/v<version>/?_schema)
+type wapiSchema struct {
+	ibclient.IBBase   `json:"-"`
+	RequestedVersion  string   `json:"requested_version,omitempty"`
+	SupportedVersions []string `json:"supported_versions,omitempty"`
+}
+
+// ObjectType is empty, so the request goes to the WAPI root
+func (wapiSchema) ObjectType() string {
+	return ""
+}
+
+// SelfCheck verifies that the configured grid can serve the webhook: the WAPI version
+// is supported, the view exists and the zones matching the domain filter are readable.
+// Write access is not verified, missing write permissions fail the first apply.
+// Every check is logged; the returned error joins all fatal misconfigurations.
+func (p *Provider) SelfCheck() error {
+	var errs []error
+	check := func(name string, err error) {
+		if err != nil {
+			log.WithError(err).Errorf("self-check %s: failed", name)
+			errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			return
+		}
+		log.Infof("self-check %s: ok", name)
+	}
+
+	check("WAPI version", p.checkVersion())
+	check("view", p.checkView())
+	check("zones", p.checkZones())
+
+	return errors.Join(errs...)
+}
+
+func (p *Provider) checkVersion() error {
+	var schema wapiSchema
+	queryParams := ibclient.NewQueryParams(false, map[string]string{"_schema": "1"})
+	if err := p.client.GetObject(&schema, "", queryParams, &schema); err != nil {
+		return fmt.Errorf("could not fetch WAPI schema for version '%s': %w", p.config.Version, err)
+	}
+	if len(schema.SupportedVersions) > 0 && !slices.Contains(schema.SupportedVersions, p.config.Version) {
+		return fmt.Errorf("WAPI version '%s' is not supported by the grid, supported versions: %s",
+			p.config.Version, strings.Join(schema.SupportedVersions, ", "))
+	}
+	return nil
+}
+
+func (p *Provider) checkView() error {
+	if p.config.View == "" {
+		return nil
+	}
+	var res []ibclient.View
+	queryParams := ibclient.NewQueryParams(false, map[string]string{"name": p.config.View})
+	err := p.client.GetObject(&ibclient.View{}, "", queryParams, &res)
+	if err != nil && !isNotFoundError(err) {
+		return fmt.Errorf("could not fetch view '%s': %w", p.config.View, err)
+	}
+	if len(res) == 0 {
+		return fmt.Errorf("view '%s' does not exist", p.config.View)
+	}
+	return nil
+}
+
+// checkZones verifies that the zones matching the domain filter are visible and that their
+// records can be read. WAPI offers no way to verify write permissions without mutating the
+// grid, so these surface on the first apply.
+func (p *Provider) checkZones() error {
+	zones, err := p.zones()
+	if err != nil {
+		return fmt.Errorf("could not fetch zones: %w", err)
+	}
+	if len(zones) == 0 {
+		if p.domainFilter.IsConfigured() {
+			return fmt.Errorf("no authoritative zones in view '%s' match the domain filter, check the filter and the permissions of the WAPI user", p.config.View)
+		}
+		log.Warnf("self-check zones: no authoritative zones found in view '%s'", p.config.View)
+		return nil
+	}
+
+	var errs []error
+	fqdns := make([]string, 0, len(zones))
+	for _, zone := range zones {
+		fqdns = append(fqdns, zone.Fqdn)
+		var res []ibclient.RecordA
+		obj := ibclient.NewEmptyRecordA()
+		obj.Zone = zone.Fqdn
+		err = p.client.GetObject(obj, "", recordQueryParams(zone.Fqdn, p.config.View), &res)
+		if err != nil && !isNotFoundError(err) {
+			errs = append(errs, fmt.Errorf("could not read records of zone '%s': %w", zone.Fqdn, err))
+		}
+	}
+	log.Infof("self-check zones: %d zones readable, write access is not verified: %s", len(zones), strings.Join(fqdns, ", "))
+	return errors.Join(errs...)
+}
@@ -0,0 +1,339 @@
+package infoblox
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	ibclient "github.com/infobloxopen/infoblox-go-client/v2"
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/external-dns/endpoint"
+
+	"github.com/AbsaOSS/external-dns-infoblox-webhook/pkg/webhook"
+)
+
+const (
+	snapshotExt        = ".jsonl"
+	snapshotTimeFormat = "20060102T150405.000000000Z"
+)
+
+var (
+	// ErrSnapshotsDisabled is returned by snapshot operations when INFOBLOX_SNAPSHOT_DIR is not set
+	ErrSnapshotsDisabled error = &statusError{"SnapshotsDisabled", http.StatusNotImplemented,
+		"snapshots are disabled, set INFOBLOX_SNAPSHOT_DIR to enable them"}
+	// ErrSnapshotNotFound is returned when the requested snapshot doesn't exist
+	ErrSnapshotNotFound error = &statusError{"SnapshotNotFound", http.StatusNotFound, "snapshot not found"}
+)
+
+// SnapshotEntry is the state of a record before a single change, stored as one JSON line of a snapshot
+type SnapshotEntry struct {
+	Action     string `json:"action"`
+	Name       string `json:"name"`
+	RecordType string `json:"recordType"`
+	Zone       string `json:"zone"`
+	View       string `json:"view"`
+	Ref        string `json:"ref,omitempty"`
+	// Derived marks PTR records changed alongside A records by INFOBLOX_CREATE_PTR
+	Derived bool `json:"derived,omitempty"`
+	// Before is the record stored in Infoblox before the change, nil if it didn't exist
+	Before *endpoint.Endpoint `json:"before,omitempty"`
+	// BeforeSettings are the settings of the record before the change which Before doesn't hold
+	BeforeSettings *RecordSettings `json:"beforeSettings,omitempty"`
+	// After is the requested record, nil for deletions
+	After *endpoint.Endpoint `json:"after,omitempty"`
+}
+
+// RecordSettings are the settings of a WAPI record which its endpoint doesn't hold, restored by a rollback
+type RecordSettings struct {
+	UseTTL  *bool       `json:"useTTL,omitempty"`
+	Comment *string     `json:"comment,omitempty"`
+	EA      ibclient.EA `json:"extattrs,omitempty"`
+}
+
+// recordSettings returns the settings of the record
+func recordSettings(obj ibclient.IBObject) *RecordSettings {
+	switch r := obj.(type) {
+	case *ibclient.RecordA:
+		return &RecordSettings{UseTTL: r.UseTtl, Comment: r.Comment, EA: r.Ea}
+	case *ibclient.RecordTXT:
+		return &RecordSettings{UseTTL: r.UseTtl, Comment: r.Comment, EA: r.Ea}
+	case *ibclient.RecordCNAME:
+		return &RecordSettings{UseTTL: r.UseTtl, Comment: r.Comment, EA: r.Ea}
+	case *ibclient.RecordPTR:
+		return &RecordSettings{UseTTL: r.UseTtl, Comment: r.Comment, EA: r.Ea}
+	}
+	return nil
+}
+
+// apply sets the settings on the record. A record not using its own TTL gets no TTL, so it inherits
+// the TTL of the zone again.
+func (s *RecordSettings) apply(obj ibclient.IBObject) {
+	if s == nil {
+		return
+	}
+	var ttl **uint32
+	switch r := obj.(type) {
+	case *ibclient.RecordA:
+		r.UseTtl, r.Comment, r.Ea, ttl = s.UseTTL, s.Comment, s.EA, &r.Ttl
+	case *ibclient.RecordTXT:
+		r.UseTtl, r.Comment, r.Ea, ttl = s.UseTTL, s.Comment, s.EA, &r.Ttl
+	case *ibclient.RecordCNAME:
+		r.UseTtl, r.Comment, r.Ea, ttl = s.UseTTL, s.Comment, s.EA, &r.Ttl
+	case *ibclient.RecordPTR:
+		r.UseTtl, r.Comment, r.Ea, ttl = s.UseTTL, s.Comment, s.EA, &r.Ttl
+	default:
+		return
+	}
+	if s.UseTTL != nil && !*s.UseTTL {
+		*ttl = nil
+	}
+}
+
+// endpointValues returns the target and TTL of the record as its endpoint holds them,
+// the target of a PTR endpoint is the address instead of the name
+func endpointValues(obj ibclient.IBObject) (string, int64) {
+	if r, ok := obj.(*ibclient.RecordPTR); ok {
+		return AsString(r.Ipv4Addr), AsInt64(r.Ttl)
+	}
+	return recordValues(obj)
+}
+
+// snapshot is written while a plan is applied. The file is created with the first change,
+// so plans which don't change anything leave no snapshot behind.
+type snapshot struct {
+	dir      string
+	id       string
+	f        *os.File
+	n        int
+	maxCount int
+	maxAge   time.Duration
+}
+
+// newSnapshot returns the snapshot of the plan being applied or nil when snapshots are disabled
+func (p *Provider) newSnapshot() *snapshot {
+	if p.config.SnapshotDir == "" {
+		return nil
+	}
+	return &snapshot{
+		dir:      p.config.SnapshotDir,
+		id:       time.Now().UTC().Format(snapshotTimeFormat),
+		maxCount: p.config.SnapshotMaxCount,
+		maxAge:   p.config.SnapshotMaxAge,
+	}
+}
+
+// add stores the current state of the record, it must succeed before the record is changed
+func (s *snapshot) add(zone, view string, change *infobloxChange, record *infobloxRecordSet, ref string) error {
+	if s == nil {
+		return nil
+	}
+	entry := SnapshotEntry{
+		Action:     change.Action,
+		Name:       change.Endpoint.DNSName,
+		RecordType: change.Endpoint.RecordType,
+		Zone:       zone,
+		View:       view,
+		Ref:        ref,
+		Derived:    change.derivedFrom != nil,
+	}
+	if change.Action != infobloxDelete {
+		entry.After = change.Endpoint
+	}
+	if current := currentRecord(record); current != nil && change.Action != infobloxCreate {
+		target, ttl := endpointValues(current)
+		entry.Before = endpoint.NewEndpointWithTTL(change.Endpoint.DNSName, change.Endpoint.RecordType, endpoint.TTL(ttl), target)
+		entry.BeforeSettings = recordSettings(current)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	if s.f == nil {
+		if err = os.MkdirAll(s.dir, 0o700); err != nil {
+			return err
+		}
+		s.f, err = os.OpenFile(filepath.Join(s.dir, s.id+snapshotExt), os.O_APPEND|os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err != nil {
+			return err
+		}
+	}
+	if _, err = s.f.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	s.n++
+	return s.f.Sync()
+}
+
+func (s *snapshot) close() {
+	if s == nil || s.f == nil {
+		return
+	}
+	if err := s.f.Close(); err != nil {
+		log.WithError(err).Errorf("could not close snapshot '%s'", s.id)
+		return
+	}
+	log.Infof("stored snapshot '%s' of %d changes", s.id, s.n)
+	s.prune()
+}
+
+// prune deletes the snapshots beyond the newest maxCount and the snapshots older than maxAge
+func (s *snapshot) prune() {
+	if s.maxCount <= 0 && s.maxAge <= 0 {
+		return
+	}
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		log.WithError(err).Error("could not list snapshots to prune")
+		return
+	}
+	var ids []string
+	for _, file := range files {
+		if id, ok := strings.CutSuffix(file.Name(), snapshotExt); ok && !file.IsDir() {
+			if _, err = time.Parse(snapshotTimeFormat, id); err == nil {
+				ids = append(ids, id)
+			}
+		}
+	}
+	// the IDs sort by creation time, newest first
+	slices.Sort(ids)
+	slices.Reverse(ids)
+	for i, id := range ids {
+		created, _ := time.Parse(snapshotTimeFormat, id)
+		if (s.maxCount <= 0 || i < s.maxCount) && (s.maxAge <= 0 || time.Since(created) <= s.maxAge) {
+			continue
+		}
+		if err = os.Remove(filepath.Join(s.dir, id+snapshotExt)); err != nil {
+			log.WithError(err).Errorf("could not delete snapshot '%s'", id)
+			continue
+		}
+		log.Infof("deleted snapshot '%s' beyond the retention", id)
+	}
+}
+
+// Snapshots lists the stored snapshots, oldest first
+func (p *Provider) Snapshots() ([]webhook.SnapshotInfo, error) {
+	if p.config.SnapshotDir == "" {
+		return nil, ErrSnapshotsDisabled
+	}
+	files, err := os.ReadDir(p.config.SnapshotDir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("could not list snapshots: %w", err)
+	}
+	snapshots := []webhook.SnapshotInfo{}
+	for _, file := range files {
+		id, ok := strings.CutSuffix(file.Name(), snapshotExt)
+		if !ok || file.IsDir() {
+			continue
+		}
+		entries, err := p.readSnapshot(id)
+		if err != nil {
+			return nil, err
+		}
+		created, err := time.Parse(snapshotTimeFormat, id)
+		if err != nil {
+			continue
+		}
+		snapshots = append(snapshots, webhook.SnapshotInfo{ID: id, Created: created, Changes: len(entries)})
+	}
+	slices.SortFunc(snapshots, func(a, b webhook.SnapshotInfo) int { return a.Created.Compare(b.Created) })
+	return snapshots, nil
+}
+
+func (p *Provider) readSnapshot(id string) ([]SnapshotEntry, error) {
+	if id == "" || filepath.Base(id) != id || strings.HasPrefix(id, ".") {
+		return nil, fmt.Errorf("%w: '%s'", ErrSnapshotNotFound, id)
+	}
+	f, err := os.Open(filepath.Join(p.config.SnapshotDir, id+snapshotExt))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: '%s'", ErrSnapshotNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read snapshot '%s': %w", id, err)
+	}
+	defer f.Close()
+
+	var entries []SnapshotEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		entry := SnapshotEntry{}
+		if err = json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("could not parse snapshot '%s': %w", id, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, fmt.Errorf("could not read snapshot '%s': %w", id, err)
+	}
+	return entries, nil
+}
+
+// Rollback reverts the changes stored in the snapshot: created records are deleted, deleted
+// records are created again and updated records get their previous values back. The rollback
+// is snapshotted itself and subject to the policy and the deletion thresholds. It is requested by
+// an admin, so it is applied in zones requiring approval as well. With INFOBLOX_CREATE_PTR,
+// the PTR records are derived from the reverted A records again.
+func (p *Provider) Rollback(_ context.Context, id string) error {
+	if p.config.SnapshotDir == "" {
+		return ErrSnapshotsDisabled
+	}
+	entries, err := p.readSnapshot(id)
+	if err != nil {
+		return err
+	}
+
+	var changes []*infobloxChange
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if entry.Derived && p.config.CreatePTR {
+			continue
+		}
+		switch {
+		case entry.Action == infobloxCreate && entry.After != nil:
+			changes = append(changes, &infobloxChange{Action: infobloxDelete, Endpoint: entry.After})
+		case entry.Action == infobloxDelete && entry.Before != nil:
+			changes = append(changes, &infobloxChange{Action: infobloxCreate, Endpoint: entry.Before, settings: entry.BeforeSettings})
+		case entry.Action == infobloxUpdate && entry.Before != nil:
+			changes = append(changes, &infobloxChange{Action: infobloxUpdate, Endpoint: entry.Before, settings: entry.BeforeSettings})
+		default:
+			log.Warnf("snapshot '%s': can't revert %s of %s record '%s', it didn't exist before the change",
+				id, entry.Action, entry.RecordType, entry.Name)
+		}
+	}
+	log.Infof("rolling back snapshot '%s' with %d changes", id, len(changes))
+	if len(changes) == 0 {
+		return nil
+	}
+	changesByZone, err := p.zoneChanges(changes)
+	if err != nil {
+		return err
+	}
+	return p.executeChanges(changesByZone)
+}
@@ -0,0 +1,196 @@
+package zonefile
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+	log "github.com/sirupsen/logrus"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// txtChunk is the longest character string of a TXT record
+const txtChunk = 255
+
+// ManagedRecords are the record types read from and written to zone files
+var ManagedRecords = []string{
+	endpoint.RecordTypeA,
+	endpoint.RecordTypeAAAA,
+	endpoint.RecordTypeCNAME,
+	endpoint.RecordTypeTXT,
+	endpoint.RecordTypePTR,
+}
+
+// Write writes the endpoints as an RFC 1035 zone file of the zone, one resource record per target
+func Write(w io.Writer, zone string, endpoints []*endpoint.Endpoint) error {
+	sorted := make([]*endpoint.Endpoint, len(endpoints))
+	copy(sorted, endpoints)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].DNSName != sorted[j].DNSName {
+			return sorted[i].DNSName < sorted[j].DNSName
+		}
+		return sorted[i].RecordType < sorted[j].RecordType
+	})
+
+	if _, err := fmt.Fprintf(w, "$ORIGIN %s\n", dns.Fqdn(zone)); err != nil {
+		return err
+	}
+	for _, ep := range sorted {
+		rrs, err := ToRRs(ep)
+		if err != nil {
+			return err
+		}
+		for _, rr := range rrs {
+			if _, err = fmt.Fprintln(w, rr.String()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ToRRs converts the endpoint into a resource record per target
+func ToRRs(ep *endpoint.Endpoint) ([]dns.RR, error) {
+	rrs := make([]dns.RR, 0, len(ep.Targets))
+	for _, target := range ep.Targets {
+		hdr := dns.RR_Header{Name: dns.Fqdn(ep.DNSName), Class: dns.ClassINET, Ttl: uint32(ep.RecordTTL)}
+		var rr dns.RR
+		switch ep.RecordType {
+		case endpoint.RecordTypeA, endpoint.RecordTypeAAAA:
+			ip := net.ParseIP(target)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address '%s' of %s record '%s'", target, ep.RecordType, ep.DNSName)
+			}
+			if ep.RecordType == endpoint.RecordTypeA {
+				hdr.Rrtype = dns.TypeA
+				rr = &dns.A{Hdr: hdr, A: ip}
+			} else {
+				hdr.Rrtype = dns.TypeAAAA
+				rr = &dns.AAAA{Hdr: hdr, AAAA: ip}
+			}
+		case endpoint.RecordTypeCNAME:
+			hdr.Rrtype = dns.TypeCNAME
+			rr = &dns.CNAME{Hdr: hdr, Target: dns.Fqdn(target)}
+		case endpoint.RecordTypePTR:
+			hdr.Rrtype = dns.TypePTR
+			rr = &dns.PTR{Hdr: hdr, Ptr: dns.Fqdn(target)}
+		case endpoint.RecordTypeTXT:
+			hdr.Rrtype = dns.TypeTXT
+			rr = &dns.TXT{Hdr: hdr, Txt: splitTXT(target)}
+		default:
+			return nil, fmt.Errorf("record type '%s' of '%s' can't be written to a zone file", ep.RecordType, ep.DNSName)
+		}
+		rrs = append(rrs, rr)
+	}
+	return rrs, nil
+}
+
+// splitTXT splits the text into character strings of at most 255 bytes
+func splitTXT(text string) []string {
+	chunks := []string{}
+	for len(text) > txtChunk {
+		chunks = append(chunks, text[:txtChunk])
+		text = text[txtChunk:]
+	}
+	return append(chunks, text)
+}
+
+// Parse reads the zone file into endpoints, merging the records of the same name and type into one
+// endpoint. Relative names are completed by the origin. Record types other than ManagedRecords,
+// like SOA and NS, are skipped.
+func Parse(r io.Reader, origin, file string) ([]*endpoint.Endpoint, error) {
+	var endpoints []*endpoint.Endpoint
+	index := map[string]*endpoint.Endpoint{}
+	zp := dns.NewZoneParser(r, dns.Fqdn(origin), file)
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		hdr := rr.Header()
+		name := strings.TrimSuffix(hdr.Name, ".")
+		recordType := dns.TypeToString[hdr.Rrtype]
+		var target string
+		switch v := rr.(type) {
+		case *dns.A:
+			target = v.A.String()
+		case *dns.AAAA:
+			target = v.AAAA.String()
+		case *dns.CNAME:
+			target = strings.TrimSuffix(v.Target, ".")
+		case *dns.PTR:
+			target = strings.TrimSuffix(v.Ptr, ".")
+		case *dns.TXT:
+			target = strings.Join(v.Txt, "")
+		default:
+			log.Warnf("skipping %s record '%s' of zone file '%s'", recordType, name, file)
+			continue
+		}
+		key := name + "/" + recordType
+		if ep, ok := index[key]; ok {
+			ep.Targets = append(ep.Targets, target)
+			continue
+		}
+		ep := endpoint.NewEndpointWithTTL(name, recordType, endpoint.TTL(hdr.Ttl), target)
+		index[key] = ep
+		endpoints = append(endpoints, ep)
+	}
+	if err := zp.Err(); err != nil {
+		return nil, err
+	}
+	for _, ep := range endpoints {
+		sort.Sort(ep.Targets)
+	}
+	return endpoints, nil
+}
+
+// WriteDiff writes the changes as records prefixed by '-' for removed and '+' for added records.
+// Updates are written as the removed old and the added new record.
+func WriteDiff(w io.Writer, changes *plan.Changes) error {
+	write := func(prefix string, endpoints []*endpoint.Endpoint) error {
+		for _, ep := range endpoints {
+			rrs, err := ToRRs(ep)
+			if err != nil {
+				return err
+			}
+			for _, rr := range rrs {
+				if _, err = fmt.Fprintf(w, "%s %s\n", prefix, rr.String()); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := write("+", changes.Create); err != nil {
+		return err
+	}
+	for i := range changes.UpdateOld {
+		if err := write("-", changes.UpdateOld[i:i+1]); err != nil {
+			return err
+		}
+		if i < len(changes.UpdateNew) {
+			if err := write("+", changes.UpdateNew[i:i+1]); err != nil {
+				return err
+			}
+		}
+	}
+	return write("-", changes.Delete)
+}
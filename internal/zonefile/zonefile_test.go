@@ -0,0 +1,86 @@
+package zonefile
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/plan"
+)
+
+func TestWriteAndParse(t *testing.T) {
+	endpoints := []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("www.example.com", endpoint.RecordTypeA, 300, "10.0.0.1", "10.0.0.2"),
+		endpoint.NewEndpointWithTTL("example.com", endpoint.RecordTypeTXT, 0, "heritage=external-dns,external-dns/owner=default"),
+		endpoint.NewEndpointWithTTL("long.example.com", endpoint.RecordTypeTXT, 60, strings.Repeat("x", 300)),
+		endpoint.NewEndpointWithTTL("mail.example.com", endpoint.RecordTypeCNAME, 3600, "mx.example.org"),
+	}
+
+	out := &bytes.Buffer{}
+	assert.NoError(t, Write(out, "example.com", endpoints))
+	assert.True(t, strings.HasPrefix(out.String(), "$ORIGIN example.com.\n"+
+		"example.com.\t0\tIN\tTXT\t\"heritage=external-dns,external-dns/owner=default\"\n"))
+	assert.Contains(t, out.String(), "www.example.com.\t300\tIN\tA\t10.0.0.1\nwww.example.com.\t300\tIN\tA\t10.0.0.2\n")
+
+	parsed, err := Parse(out, "example.com", "example.com.zone")
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, endpoints, parsed)
+
+	assert.ErrorContains(t, Write(&bytes.Buffer{}, "example.com", []*endpoint.Endpoint{
+		endpoint.NewEndpoint("srv.example.com", endpoint.RecordTypeSRV, "0 50 5060 sip.example.com"),
+	}), "record type 'SRV' of 'srv.example.com' can't be written to a zone file")
+}
+
+func TestParseZoneFile(t *testing.T) {
+	zone := `$TTL 600
+@	IN SOA ns1.example.com. hostmaster.example.com. 1 3600 900 604800 300
+	IN NS ns1.example.com.
+www	IN A 10.0.0.2
+www	300 IN A 10.0.0.1
+ftp.example.com.	IN CNAME www
+`
+	parsed, err := Parse(strings.NewReader(zone), "example.com", "example.com.zone")
+	assert.NoError(t, err)
+	assert.Equal(t, []*endpoint.Endpoint{
+		endpoint.NewEndpointWithTTL("www.example.com", endpoint.RecordTypeA, 600, "10.0.0.1", "10.0.0.2"),
+		endpoint.NewEndpointWithTTL("ftp.example.com", endpoint.RecordTypeCNAME, 600, "www.example.com"),
+	}, parsed)
+
+	_, err = Parse(strings.NewReader("www IN A not-an-address\n"), "example.com", "example.com.zone")
+	assert.ErrorContains(t, err, "example.com.zone")
+}
+
+func TestWriteDiff(t *testing.T) {
+	changes := &plan.Changes{
+		Create:    []*endpoint.Endpoint{endpoint.NewEndpointWithTTL("new.example.com", endpoint.RecordTypeA, 300, "10.0.0.3")},
+		UpdateOld: []*endpoint.Endpoint{endpoint.NewEndpointWithTTL("www.example.com", endpoint.RecordTypeA, 300, "10.0.0.1")},
+		UpdateNew: []*endpoint.Endpoint{endpoint.NewEndpointWithTTL("www.example.com", endpoint.RecordTypeA, 600, "10.0.0.1")},
+		Delete:    []*endpoint.Endpoint{endpoint.NewEndpointWithTTL("old.example.com", endpoint.RecordTypeCNAME, 300, "www.example.com")},
+	}
+	out := &bytes.Buffer{}
+	assert.NoError(t, WriteDiff(out, changes))
+	assert.Equal(t, "+ new.example.com.\t300\tIN\tA\t10.0.0.3\n"+
+		"- www.example.com.\t300\tIN\tA\t10.0.0.1\n"+
+		"+ www.example.com.\t600\tIN\tA\t10.0.0.1\n"+
+		"- old.example.com.\t300\tIN\tCNAME\twww.example.com.\n", out.String())
+}
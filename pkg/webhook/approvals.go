@@ -0,0 +1,121 @@
+package webhook
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"sigs.k8s.io/external-dns/endpoint"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// errApprovalsNotSupported is returned when the provider doesn't queue changes for approval
+var errApprovalsNotSupported = errors.New("the provider doesn't support approvals")
+
+// PendingChange is a change of a zone requiring approval, waiting in the approval queue
+type PendingChange struct {
+	ID       string             `json:"id"`
+	Zone     string             `json:"zone"`
+	Action   string             `json:"action"`
+	Endpoint *endpoint.Endpoint `json:"endpoint"`
+	// Operation is the WAPI request the change translated to when it was queued,
+	// the change is translated again when it is approved
+	Operation Operation `json:"operation"`
+	Created   time.Time `json:"created"`
+	Expires   time.Time `json:"expires"`
+}
+
+// ApprovalProvider is implemented by providers which queue changes of sensitive zones for approval
+type ApprovalProvider interface {
+	PendingChanges() ([]PendingChange, error)
+	Approve(ctx context.Context, id string) error
+	Reject(id string) error
+}
+
+func approvalProvider(prov provider.Provider) (ApprovalProvider, error) {
+	ap, ok := prov.(ApprovalProvider)
+	if !ok {
+		return nil, errApprovalsNotSupported
+	}
+	return ap, nil
+}
+
+// PendingChanges handles the get request listing the changes waiting for approval
+func (p *Webhook) PendingChanges(w http.ResponseWriter, r *http.Request) {
+	prov, release := p.acquire()
+	defer release()
+	ap, err := approvalProvider(prov)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	pending, err := ap.PendingChanges()
+	if err != nil {
+		requestLog(r).WithField(logFieldError, err).Error("error listing pending changes")
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set(contentTypeHeader, contentTypeJSON)
+	if err = json.NewEncoder(w).Encode(pending); err != nil {
+		requestLog(r).WithField(logFieldError, err).Error("error encoding pending changes")
+	}
+}
+
+// Approve handles the post request applying a pending change
+func (p *Webhook) Approve(w http.ResponseWriter, r *http.Request) {
+	prov, release := p.acquire()
+	defer release()
+	ap, err := approvalProvider(prov)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	id := chi.URLParam(r, "id")
+	requestLog(r).Infof("approving pending change '%s'", id)
+	if err = ap.Approve(r.Context(), id); err != nil {
+		requestLog(r).WithField(logFieldError, err).Error("error applying approved change")
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Reject handles the post request discarding a pending change
+func (p *Webhook) Reject(w http.ResponseWriter, r *http.Request) {
+	prov, release := p.acquire()
+	defer release()
+	ap, err := approvalProvider(prov)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	id := chi.URLParam(r, "id")
+	requestLog(r).Infof("rejecting pending change '%s'", id)
+	if err = ap.Reject(id); err != nil {
+		requestLog(r).WithField(logFieldError, err).Error("error rejecting change")
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,151 @@
+package webhook
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"crypto/subtle"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// errUnauthorized is returned to callers failing the authentication
+	errUnauthorized = errors.New("unauthorized")
+	// errForbidden is returned to callers failing the authentication of the admin endpoints
+	errForbidden = errors.New("forbidden")
+)
+
+// Authenticator verifies the callers of the webhook API by a bearer token and/or a client certificate.
+// Place it after Health, so the health check stays open.
+type Authenticator struct {
+	token             *cachedToken
+	requireClientCert bool
+	clientNames       []string
+	admin             bool
+}
+
+// NewAuthenticator creates an Authenticator. The bearer token is read from tokenFile, which is
+// reread when it changes. With requireClientCert, callers must present a client certificate verified
+// by the TLS server and, if clientNames is set, with one of the names as common name or DNS name.
+func NewAuthenticator(tokenFile string, requireClientCert bool, clientNames []string) *Authenticator {
+	a := &Authenticator{requireClientCert: requireClientCert, clientNames: clientNames}
+	if tokenFile != "" {
+		a.token = &cachedToken{path: tokenFile}
+	}
+	return a
+}
+
+// NewAdminAuthenticator creates an Authenticator of the admin endpoints, which change records outside
+// of the plans of external-dns. The admin credentials are separate from the credentials of external-dns:
+// the admin token and/or a client certificate with one of the admin clientNames. Without any of them,
+// every request is refused.
+func NewAdminAuthenticator(tokenFile string, clientNames []string) *Authenticator {
+	a := NewAuthenticator(tokenFile, len(clientNames) > 0, clientNames)
+	a.admin = true
+	return a
+}
+
+// Middleware rejects requests which fail the authentication with status 401, or 403 for the admin endpoints
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := a.authenticate(r); err != nil {
+			if a.admin {
+				requestLog(r).WithField(logFieldError, err).Warn("rejecting request to admin endpoint")
+				writeError(w, r, fmt.Errorf("%w: %v", errForbidden, err))
+				return
+			}
+			requestLog(r).WithField(logFieldError, err).Warn("rejecting unauthenticated request")
+			if a.token != nil {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+			}
+			writeError(w, r, fmt.Errorf("%w: %v", errUnauthorized, err))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (a *Authenticator) authenticate(r *http.Request) error {
+	if a.admin && a.token == nil && !a.requireClientCert {
+		return errors.New("admin endpoints are disabled, no admin credentials are configured")
+	}
+	if a.requireClientCert {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			return errors.New("client certificate required")
+		}
+		if cert := r.TLS.VerifiedChains[0][0]; !a.allowedClient(cert) {
+			return fmt.Errorf("client certificate '%s' is not allowed", cert.Subject.CommonName)
+		}
+	}
+	if a.token != nil {
+		expected, err := a.token.get()
+		if err != nil {
+			return err
+		}
+		token, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !found || subtle.ConstantTimeCompare([]byte(token), []byte(expected)) != 1 {
+			return errors.New("invalid bearer token")
+		}
+	}
+	return nil
+}
+
+func (a *Authenticator) allowedClient(cert *x509.Certificate) bool {
+	if len(a.clientNames) == 0 {
+		return true
+	}
+	return slices.Contains(a.clientNames, cert.Subject.CommonName) ||
+		slices.ContainsFunc(cert.DNSNames, func(name string) bool { return slices.Contains(a.clientNames, name) })
+}
+
+// cachedToken caches the token read from a file until the file is modified
+type cachedToken struct {
+	path    string
+	mu      sync.Mutex
+	modTime time.Time
+	token   string
+}
+
+func (t *cachedToken) get() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return "", fmt.Errorf("could not read token file: %w", err)
+	}
+	if !info.ModTime().Equal(t.modTime) {
+		data, err := os.ReadFile(t.path)
+		if err != nil {
+			return "", fmt.Errorf("could not read token file: %w", err)
+		}
+		t.token = strings.TrimSpace(string(data))
+		t.modTime = info.ModTime()
+	}
+	if t.token == "" {
+		return "", errors.New("token file is empty")
+	}
+	return t.token, nil
+}
@@ -0,0 +1,126 @@
+package webhook
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+const (
+	contentTypeJSON       = "application/json"
+	errorCodeInternal     = "InternalError"
+	errorCodeNotSupported = "NotSupported"
+	errorCodeUnauthorized = "Unauthorized"
+	errorCodeForbidden    = "Forbidden"
+)
+
+// ProviderError is implemented by the provider errors which carry their own error code and
+// response status. Errors not implementing it are reported as internal errors.
+type ProviderError interface {
+	error
+	Code() string
+	HTTPStatus() int
+}
+
+// RecordFailure is implemented by the provider errors about a single record, whose details are
+// added to the error response
+type RecordFailure interface {
+	ProviderError
+	Record() (name, recordType, zone string)
+	WAPIText() string
+}
+
+// PartialFailure is implemented by the provider errors of plans which were applied partially,
+// the outcome of every attempted change is added to the error response
+type PartialFailure interface {
+	error
+	ChangeOutcomes() []ChangeOutcome
+}
+
+// ErrorResponse is the document returned when the provider fails to serve a request
+type ErrorResponse struct {
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	Record     string `json:"record,omitempty"`
+	RecordType string `json:"recordType,omitempty"`
+	Zone       string `json:"zone,omitempty"`
+	WAPIError  string `json:"wapiError,omitempty"`
+	// Changes reports every attempted change when the provider continued after failures
+	Changes []ChangeOutcome `json:"changes,omitempty"`
+}
+
+// ChangeOutcome is the result of a single change of a partially failed apply
+type ChangeOutcome struct {
+	Action     string `json:"action"`
+	Record     string `json:"record"`
+	RecordType string `json:"recordType"`
+	Target     string `json:"target,omitempty"`
+	Zone       string `json:"zone,omitempty"`
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+}
+
+// newErrorResponse translates the provider error into the response document and its status code.
+// When several changes failed, the record details and status code are taken from the first failure.
+func newErrorResponse(err error) (int, ErrorResponse) {
+	resp := ErrorResponse{
+		Code:    errorCodeInternal,
+		Message: err.Error(),
+	}
+
+	switch {
+	case errors.Is(err, errUnauthorized):
+		resp.Code = errorCodeUnauthorized
+		return http.StatusUnauthorized, resp
+	case errors.Is(err, errForbidden):
+		resp.Code = errorCodeForbidden
+		return http.StatusForbidden, resp
+	case errors.Is(err, errPlanNotSupported), errors.Is(err, errSnapshotsNotSupported), errors.Is(err, errApprovalsNotSupported):
+		resp.Code = errorCodeNotSupported
+		return http.StatusNotImplemented, resp
+	}
+
+	var partialErr PartialFailure
+	if errors.As(err, &partialErr) {
+		resp.Changes = partialErr.ChangeOutcomes()
+	}
+
+	var providerErr ProviderError
+	if !errors.As(err, &providerErr) {
+		return http.StatusInternalServerError, resp
+	}
+	resp.Code = providerErr.Code()
+	if recordErr, ok := providerErr.(RecordFailure); ok {
+		resp.Record, resp.RecordType, resp.Zone = recordErr.Record()
+		resp.WAPIError = recordErr.WAPIText()
+	}
+	return providerErr.HTTPStatus(), resp
+}
+
+// writeError writes the provider error as ErrorResponse
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	status, resp := newErrorResponse(err)
+	w.Header().Set(contentTypeHeader, contentTypeJSON)
+	w.WriteHeader(status)
+	if encodeErr := json.NewEncoder(w).Encode(resp); encodeErr != nil {
+		requestLog(r).WithField(logFieldError, encodeErr).Error("error writing error response")
+	}
+}
@@ -0,0 +1,95 @@
+package webhook
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"sigs.k8s.io/external-dns/plan"
+)
+
+// errPlanNotSupported is returned when the provider can't preview changes
+var errPlanNotSupported = errors.New("the provider doesn't support plan previews")
+
+// Operation is a single WAPI request ApplyChanges would send to Infoblox
+type Operation struct {
+	Action     string `json:"action"`
+	Object     string `json:"object"`
+	Name       string `json:"name"`
+	RecordType string `json:"recordType"`
+	Target     string `json:"target"`
+	TTL        int64  `json:"ttl"`
+	Zone       string `json:"zone"`
+	// Ref is the object which is updated or deleted, empty if it doesn't exist in Infoblox
+	Ref string `json:"ref,omitempty"`
+	// Reverse marks PTR records derived from A records by INFOBLOX_CREATE_PTR
+	Reverse bool `json:"reverse,omitempty"`
+	// Queued marks operations of INFOBLOX_APPROVAL_ZONES, which ApplyChanges queues for approval instead of sending
+	Queued bool `json:"queued,omitempty"`
+}
+
+// PlanProvider is implemented by providers which can preview the operations of a plan
+type PlanProvider interface {
+	Plan(ctx context.Context, changes *plan.Changes) ([]Operation, error)
+}
+
+// Plan handles the post request previewing record changes, it returns the operations
+// ApplyChanges would send to Infoblox without applying them
+func (p *Webhook) Plan(w http.ResponseWriter, r *http.Request) {
+	if err := p.contentTypeHeaderCheck(w, r); err != nil {
+		requestLog(r).WithField(logFieldError, err).Error("content type header check failed")
+		return
+	}
+	prov, release := p.acquire()
+	defer release()
+	pp, ok := prov.(PlanProvider)
+	if !ok {
+		writeError(w, r, errPlanNotSupported)
+		return
+	}
+
+	var changes plan.Changes
+	if err := json.NewDecoder(r.Body).Decode(&changes); err != nil {
+		w.Header().Set(contentTypeHeader, contentTypePlaintext)
+		w.WriteHeader(http.StatusBadRequest)
+
+		errMsg := fmt.Sprintf("error decoding changes: %s", err.Error())
+		if _, writeError := fmt.Fprint(w, errMsg); writeError != nil {
+			requestLog(r).WithField(logFieldError, writeError).Error("error writing error message to response writer")
+		}
+		requestLog(r).WithField(logFieldError, err).Info(errMsg)
+		return
+	}
+
+	operations, err := pp.Plan(r.Context(), &changes)
+	if err != nil {
+		requestLog(r).WithField(logFieldError, err).Error("error planning changes")
+		writeError(w, r, err)
+		return
+	}
+	requestLog(r).Debugf("returning %d planned operations", len(operations))
+	w.Header().Set(contentTypeHeader, contentTypeJSON)
+	if err = json.NewEncoder(w).Encode(operations); err != nil {
+		requestLog(r).WithField(logFieldError, err).Error("error encoding operations")
+	}
+}
@@ -0,0 +1,94 @@
+package webhook
+
+/*
+Copyright 2024 The external-dns-infoblox-webhook Contributors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+
+Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"sigs.k8s.io/external-dns/provider"
+)
+
+// errSnapshotsNotSupported is returned when the provider doesn't snapshot records
+var errSnapshotsNotSupported = errors.New("the provider doesn't support snapshots")
+
+// SnapshotInfo describes a stored snapshot
+type SnapshotInfo struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+	Changes int       `json:"changes"`
+}
+
+// SnapshotProvider is implemented by providers which snapshot records before changing them
+type SnapshotProvider interface {
+	Snapshots() ([]SnapshotInfo, error)
+	Rollback(ctx context.Context, id string) error
+}
+
+func snapshotProvider(prov provider.Provider) (SnapshotProvider, error) {
+	sp, ok := prov.(SnapshotProvider)
+	if !ok {
+		return nil, errSnapshotsNotSupported
+	}
+	return sp, nil
+}
+
+// Snapshots handles the get request listing the stored snapshots
+func (p *Webhook) Snapshots(w http.ResponseWriter, r *http.Request) {
+	prov, release := p.acquire()
+	defer release()
+	sp, err := snapshotProvider(prov)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	snapshots, err := sp.Snapshots()
+	if err != nil {
+		requestLog(r).WithField(logFieldError, err).Error("error listing snapshots")
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set(contentTypeHeader, contentTypeJSON)
+	if err = json.NewEncoder(w).Encode(snapshots); err != nil {
+		requestLog(r).WithField(logFieldError, err).Error("error encoding snapshots")
+	}
+}
+
+// Rollback handles the post request reverting the changes stored in a snapshot
+func (p *Webhook) Rollback(w http.ResponseWriter, r *http.Request) {
+	prov, release := p.acquire()
+	defer release()
+	sp, err := snapshotProvider(prov)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	id := chi.URLParam(r, "id")
+	requestLog(r).Infof("rolling back snapshot '%s'", id)
+	if err = sp.Rollback(r.Context(), id); err != nil {
+		requestLog(r).WithField(logFieldError, err).Error("error rolling back snapshot")
+		writeError(w, r, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -21,8 +21,12 @@ Generated by GoLic, for more details see: https://github.com/AbsaOSS/golic
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
+	"sync"
+	"sync/atomic"
 
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	log "github.com/sirupsen/logrus"
 
 	"sigs.k8s.io/external-dns/endpoint"
@@ -36,6 +40,7 @@ const (
 	acceptHeader          = "Accept"
 	varyHeader            = "Vary"
 	healthPath            = "/healthz"
+	metricsPath           = "/metrics"
 	logFieldRequestPath   = "requestPath"
 	logFieldRequestMethod = "requestMethod"
 	logFieldError         = "error"
@@ -43,15 +48,52 @@ const (
 
 // Webhook for external dns provider
 type Webhook struct {
-	provider provider.Provider
+	provider atomic.Pointer[activeProvider]
+}
+
+// activeProvider tracks the requests served by a provider, so it is closed only after they complete
+type activeProvider struct {
+	provider.Provider
+	mu     sync.RWMutex
+	closed bool
 }
 
 // New creates a new instance of the Webhook
 func New(provider provider.Provider) *Webhook {
-	p := Webhook{provider: provider}
+	p := Webhook{}
+	p.provider.Store(&activeProvider{Provider: provider})
 	return &p
 }
 
+// SetProvider swaps the provider serving new requests. Requests in flight complete with the previous
+// provider, which is closed afterwards if it implements io.Closer.
+func (p *Webhook) SetProvider(provider provider.Provider) {
+	previous := p.provider.Swap(&activeProvider{Provider: provider})
+	go func() {
+		previous.mu.Lock()
+		previous.closed = true
+		previous.mu.Unlock()
+		if closer, ok := previous.Provider.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				log.WithError(err).Error("error closing the previous provider")
+			}
+		}
+	}()
+}
+
+// acquire returns the current provider and the function to call once the request is served
+func (p *Webhook) acquire() (provider.Provider, func()) {
+	for {
+		active := p.provider.Load()
+		active.mu.RLock()
+		if !active.closed {
+			return active.Provider, active.mu.RUnlock
+		}
+		// the provider was swapped and closed meanwhile
+		active.mu.RUnlock()
+	}
+}
+
 // Health handles the health request
 func Health(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -63,6 +105,23 @@ func Health(next http.Handler) http.Handler {
 	})
 }
 
+// MetricsHandler serves the Prometheus metrics
+func MetricsHandler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Metrics serves the Prometheus metrics ahead of the next handler
+func Metrics(next http.Handler) http.Handler {
+	metrics := MetricsHandler()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == metricsPath {
+			metrics.ServeHTTP(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (p *Webhook) contentTypeHeaderCheck(w http.ResponseWriter, r *http.Request) error {
 	return p.headerCheck(true, w, r)
 }
@@ -130,10 +189,12 @@ func (p *Webhook) Records(w http.ResponseWriter, r *http.Request) {
 
 	requestLog(r).Debug("requesting records")
 	ctx := r.Context()
-	records, err := p.provider.Records(ctx)
+	prov, release := p.acquire()
+	defer release()
+	records, err := prov.Records(ctx)
 	if err != nil {
 		requestLog(r).WithField(logFieldError, err).Error("error getting records")
-		w.WriteHeader(http.StatusInternalServerError)
+		writeError(w, r, err)
 		return
 	}
 
@@ -171,9 +232,11 @@ func (p *Webhook) ApplyChanges(w http.ResponseWriter, r *http.Request) {
 
 	requestLog(r).Debugf("requesting apply changes, create: %d , updateOld: %d, updateNew: %d, delete: %d",
 		len(changes.Create), len(changes.UpdateOld), len(changes.UpdateNew), len(changes.Delete))
-	if err := p.provider.ApplyChanges(ctx, &changes); err != nil {
-		w.Header().Set(contentTypeHeader, contentTypePlaintext)
-		w.WriteHeader(http.StatusInternalServerError)
+	prov, release := p.acquire()
+	defer release()
+	if err := prov.ApplyChanges(ctx, &changes); err != nil {
+		requestLog(r).WithField(logFieldError, err).Error("error applying changes")
+		writeError(w, r, err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
@@ -204,10 +267,12 @@ func (p *Webhook) AdjustEndpoints(w http.ResponseWriter, r *http.Request) {
 	}
 
 	log.Debugf("requesting adjust endpoints count: %d", len(pve))
-	pve, err := p.provider.AdjustEndpoints(pve)
+	prov, release := p.acquire()
+	defer release()
+	pve, err := prov.AdjustEndpoints(pve)
 	if err != nil {
-		w.Header().Set(contentTypeHeader, contentTypePlaintext)
-		w.WriteHeader(http.StatusInternalServerError)
+		requestLog(r).WithField(logFieldError, err).Error("error adjusting endpoints")
+		writeError(w, r, err)
 		return
 	}
 	out, _ := json.Marshal(&pve)
@@ -226,7 +291,9 @@ func (p *Webhook) Negotiate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	b, err := p.provider.GetDomainFilter().MarshalJSON()
+	prov, release := p.acquire()
+	defer release()
+	b, err := prov.GetDomainFilter().MarshalJSON()
 	if err != nil {
 		log.Errorf("failed to marshal domain filter, request method: %s, request path: %s", r.Method, r.URL.Path)
 		w.WriteHeader(http.StatusInternalServerError)